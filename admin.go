@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/pierreprinetti/apimock/har"
+	"github.com/pierreprinetti/apimock/store"
+)
+
+// adminPrefix is the path under which the administrative API is served. It
+// is reserved: the mocked API can't use it.
+const adminPrefix = "/__apimock/"
+
+type registry interface {
+	Records() []store.Record
+	Record(string, string) (store.Record, bool)
+	Load([]store.Record) error
+	Clear() error
+	Reset() error
+}
+
+// Admin is a middleware handler that serves the administrative API, and
+// passes the other requests on.
+type Admin struct {
+	next      http.Handler
+	resources registry
+}
+
+// newAdmin returns a new Admin instance
+func newAdmin(next http.Handler, resources registry) Admin {
+	return Admin{
+		next:      next,
+		resources: resources,
+	}
+}
+
+func (m Admin) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if !strings.HasPrefix(req.URL.Path, adminPrefix) {
+		m.next.ServeHTTP(rw, req)
+		return
+	}
+
+	switch strings.TrimPrefix(req.URL.Path, adminPrefix) {
+	case "keys":
+		if allowMethods(rw, req, http.MethodGet) {
+			m.keys(rw, req)
+		}
+	case "entry":
+		if allowMethods(rw, req, http.MethodGet) {
+			m.entry(rw, req)
+		}
+	case "entries":
+		if !allowMethods(rw, req, http.MethodGet, http.MethodPost, http.MethodDelete) {
+			return
+		}
+		switch req.Method {
+		case http.MethodGet:
+			writeJSON(rw, m.resources.Records())
+		case http.MethodPost:
+			m.load(rw, req)
+		case http.MethodDelete:
+			if err := m.resources.Clear(); err != nil {
+				storeFailed(rw, err)
+				return
+			}
+			rw.WriteHeader(http.StatusNoContent)
+		}
+	case "har":
+		if !allowMethods(rw, req, http.MethodGet, http.MethodPost) {
+			return
+		}
+		switch req.Method {
+		case http.MethodGet:
+			writeJSON(rw, recordsToHAR(m.resources.Records(), baseURL(req)))
+		case http.MethodPost:
+			m.importHAR(rw, req)
+		}
+	case "reset":
+		if !allowMethods(rw, req, http.MethodPost) {
+			return
+		}
+		if err := m.resources.Reset(); err != nil {
+			storeFailed(rw, err)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(rw, req)
+	}
+}
+
+// keys lists the method and the key of every entry.
+func (m Admin) keys(rw http.ResponseWriter, _ *http.Request) {
+	type entryKey struct {
+		Method string `json:"method"`
+		Path   string `json:"path"`
+	}
+
+	records := m.resources.Records()
+	keys := make([]entryKey, len(records))
+	for i, r := range records {
+		keys[i] = entryKey{r.Method, r.Path}
+	}
+
+	writeJSON(rw, keys)
+}
+
+// entry dumps the entry identified by the "method" and "path" query
+// parameters. The method defaults to GET.
+func (m Admin) entry(rw http.ResponseWriter, req *http.Request) {
+	path := req.URL.Query().Get("path")
+	if path == "" {
+		http.Error(rw, `missing query parameter "path"`, http.StatusBadRequest)
+		return
+	}
+
+	method := strings.ToUpper(req.URL.Query().Get("method"))
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	r, ok := m.resources.Record(method, path)
+	if !ok {
+		http.NotFound(rw, req)
+		return
+	}
+
+	writeJSON(rw, r)
+}
+
+// load saves the records in the request body, a JSON array.
+func (m Admin) load(rw http.ResponseWriter, req *http.Request) {
+	var records []store.Record
+	if err := json.NewDecoder(req.Body).Decode(&records); err != nil {
+		http.Error(rw, fmt.Sprintf("invalid records: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := m.resources.Load(records); err != nil {
+		storeFailed(rw, err)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// importHAR saves the responses of the HTTP Archive in the request body.
+func (m Admin) importHAR(rw http.ResponseWriter, req *http.Request) {
+	var archive har.HAR
+	if err := json.NewDecoder(req.Body).Decode(&archive); err != nil {
+		http.Error(rw, fmt.Sprintf("invalid HAR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	records, err := recordsFromHAR(archive)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := m.resources.Load(records); err != nil {
+		storeFailed(rw, err)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// allowMethods reports whether the request method is one of the given ones.
+// If it is not, allowMethods answers the request: pre-flight requests with
+// 204, the others with 405.
+func allowMethods(rw http.ResponseWriter, req *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if req.Method == method {
+			return true
+		}
+	}
+
+	if req.Method == http.MethodOptions {
+		optionsHandler(rw, req)
+		return false
+	}
+
+	rw.Header().Set("Allow", strings.Join(append(methods, http.MethodOptions), ", "))
+	http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
+}
+
+// writeJSON answers with the indented JSON encoding of v. The errors, such
+// as a client going away, are only logged: the response is already under
+// way.
+func writeJSON(rw http.ResponseWriter, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(rw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Printf("writing the JSON response: %v", err)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pierreprinetti/apimock/har"
+	"github.com/pierreprinetti/apimock/store"
+)
+
+func TestAdmin(t *testing.T) {
+	type checkFunc func(*store.Store, *httptest.ResponseRecorder) error
+	check := func(fns ...checkFunc) []checkFunc { return fns }
+
+	hasStatus := func(want int) checkFunc {
+		return func(_ *store.Store, rec *httptest.ResponseRecorder) error {
+			if rec.Code != want {
+				return fmt.Errorf("expected status %d, found %d", want, rec.Code)
+			}
+			return nil
+		}
+	}
+	hasJSON := func(want string) checkFunc {
+		return func(_ *store.Store, rec *httptest.ResponseRecorder) error {
+			if have := compactJSON(rec.Body.String()); have != want {
+				return fmt.Errorf("expected body %s, found %s", want, have)
+			}
+			return nil
+		}
+	}
+	hasHeader := func(name, want string) checkFunc {
+		return func(_ *store.Store, rec *httptest.ResponseRecorder) error {
+			if have := rec.Header().Get(name); have != want {
+				return fmt.Errorf("expected header %s %q, found %q", name, want, have)
+			}
+			return nil
+		}
+	}
+	hasEntries := func(want int) checkFunc {
+		return func(s *store.Store, _ *httptest.ResponseRecorder) error {
+			if have := len(s.Records()); have != want {
+				return fmt.Errorf("expected %d entries, found %d", want, have)
+			}
+			return nil
+		}
+	}
+	passedOn := func(want bool) checkFunc {
+		return func(_ *store.Store, rec *httptest.ResponseRecorder) error {
+			if have := rec.Body.String() == "next"; have != want {
+				return fmt.Errorf("expected passed on %v, found %v", want, have)
+			}
+			return nil
+		}
+	}
+
+	newStore := func() *store.Store {
+		s := store.New()
+		for _, k := range [...][2]string{{"GET", "/b"}, {"POST", "/a"}, {"GET", "/a"}} {
+			req := httptest.NewRequest("PUT", k[1], strings.NewReader("body"))
+			if err := s.Set(k[0], k[1], req); err != nil {
+				t.Fatalf("setting: %v", err)
+			}
+		}
+		return s
+	}
+
+	tests := [...]struct {
+		name   string
+		method string
+		target string
+		body   string
+		checks []checkFunc
+	}{
+		{
+			"passes the other requests on",
+			"GET",
+			"/a",
+			"",
+			check(passedOn(true)),
+		},
+		{
+			"lists the keys",
+			"GET",
+			"/__apimock/keys",
+			"",
+			check(
+				hasStatus(200),
+				hasHeader("Content-Type", "application/json"),
+				hasJSON(`[{"method":"GET","path":"/a"},{"method":"POST","path":"/a"},{"method":"GET","path":"/b"}]`),
+				passedOn(false),
+			),
+		},
+		{
+			"dumps an entry",
+			"GET",
+			"/__apimock/entry?method=post&path=/a",
+			"",
+			check(
+				hasStatus(200),
+				func(_ *store.Store, rec *httptest.ResponseRecorder) error {
+					var r store.Record
+					if err := json.Unmarshal(rec.Body.Bytes(), &r); err != nil {
+						return err
+					}
+					if r.Method != "POST" || r.Path != "/a" || string(r.Body) != "body" || r.ModTime.IsZero() {
+						return fmt.Errorf("unexpected record %+v", r)
+					}
+					return nil
+				},
+			),
+		},
+		{
+			"dumping an unknown entry is 404",
+			"GET",
+			"/__apimock/entry?path=/c",
+			"",
+			check(hasStatus(404)),
+		},
+		{
+			"dumping requires a path",
+			"GET",
+			"/__apimock/entry",
+			"",
+			check(hasStatus(400)),
+		},
+		{
+			"dumps every entry",
+			"GET",
+			"/__apimock/entries",
+			"",
+			check(
+				hasStatus(200),
+				func(_ *store.Store, rec *httptest.ResponseRecorder) error {
+					var records []store.Record
+					if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+						return err
+					}
+					if len(records) != 3 {
+						return fmt.Errorf("expected 3 records, found %d", len(records))
+					}
+					return nil
+				},
+			),
+		},
+		{
+			"loads entries",
+			"POST",
+			"/__apimock/entries",
+			`[{"method":"GET","path":"/c","body":"Ym9keQ=="},{"method":"GET","path":"/a","body":""}]`,
+			check(hasStatus(204), hasEntries(4)),
+		},
+		{
+			"rejects invalid entries",
+			"POST",
+			"/__apimock/entries",
+			`[{"method":"GET","path":"/c","status":42}]`,
+			check(hasStatus(400), hasEntries(3)),
+		},
+		{
+			"rejects invalid JSON",
+			"POST",
+			"/__apimock/entries",
+			`{`,
+			check(hasStatus(400), hasEntries(3)),
+		},
+		{
+			"clears everything",
+			"DELETE",
+			"/__apimock/entries",
+			"",
+			check(hasStatus(204), hasEntries(0)),
+		},
+		{
+			"exports a HAR",
+			"GET",
+			"/__apimock/har",
+			"",
+			check(
+				hasStatus(200),
+				func(_ *store.Store, rec *httptest.ResponseRecorder) error {
+					var archive har.HAR
+					if err := json.Unmarshal(rec.Body.Bytes(), &archive); err != nil {
+						return err
+					}
+					if have := len(archive.Log.Entries); have != 3 {
+						return fmt.Errorf("expected 3 entries, found %d", have)
+					}
+					if want, have := "http://example.com/a", archive.Log.Entries[0].Request.URL; want != have {
+						return fmt.Errorf("expected URL %q, found %q", want, have)
+					}
+					return nil
+				},
+			),
+		},
+		{
+			"imports a HAR",
+			"POST",
+			"/__apimock/har",
+			`{"log":{"entries":[{"request":{"method":"GET","url":"http://x/c"},"response":{"status":200,"content":{"text":"c"}}}]}}`,
+			check(hasStatus(204), hasEntries(4)),
+		},
+		{
+			"rejects invalid HARs",
+			"POST",
+			"/__apimock/har",
+			`{"log":{"entries":[{"request":{"method":"GET","url":"http://x/c"},"response":{"status":42}}]}}`,
+			check(hasStatus(400), hasEntries(3)),
+		},
+		{
+			"resets the counters",
+			"POST",
+			"/__apimock/reset",
+			"",
+			check(hasStatus(204), hasEntries(3)),
+		},
+		{
+			"rejects other methods",
+			"PUT",
+			"/__apimock/reset",
+			"",
+			check(hasStatus(405), hasHeader("Allow", "POST, OPTIONS")),
+		},
+		{
+			"answers pre-flight requests",
+			"OPTIONS",
+			"/__apimock/keys",
+			"",
+			check(hasStatus(204)),
+		},
+		{
+			"unknown endpoints are 404",
+			"GET",
+			"/__apimock/unknown",
+			"",
+			check(hasStatus(404), passedOn(false)),
+		},
+	}
+
+	next := http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.Write([]byte("next"))
+	})
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newStore()
+			rec := httptest.NewRecorder()
+			newAdmin(next, s).ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+			for _, check := range tc.checks {
+				if err := check(s, rec); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+// compactJSON strips the insignificant whitespace from a JSON document.
+func compactJSON(s string) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(s)); err != nil {
+		return s
+	}
+	return buf.String()
+}
+
+// brokenWriter is a ResponseWriter whose client went away.
+type brokenWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (brokenWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestWriteJSON(t *testing.T) {
+	t.Run("does not panic on write errors", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Errorf("unexpected panic: %v", r)
+			}
+		}()
+
+		writeJSON(brokenWriter{httptest.NewRecorder()}, []string{"a"})
+	})
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pierreprinetti/apimock/har"
+	"github.com/pierreprinetti/apimock/store"
+)
+
+// harCreator is the name of apimock in the exported archives.
+const harCreator = "apimock"
+
+// loadHAR saves the responses of the HTTP Archive file.
+func loadHAR(resources loader, file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var archive har.HAR
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return fmt.Errorf("invalid HAR file %q: %w", file, err)
+	}
+
+	records, err := recordsFromHAR(archive)
+	if err != nil {
+		return err
+	}
+
+	return resources.Load(records)
+}
+
+// recordsFromHAR converts the exchanges of the archive to records, skipping
+// the failed ones. The successive responses to the same method and URL become a sequence, so
+// that replaying the archive reproduces them in order. The host of the
+// URLs is ignored, and so are the response headers that the recorder would
+// not save, such as the CORS ones.
+func recordsFromHAR(archive har.HAR) ([]store.Record, error) {
+	var records []store.Record
+	index := make(map[[2]string]int)
+
+	for _, e := range archive.Log.Entries {
+		// Browsers record the failed requests with a zero status
+		if e.Response.Status == 0 {
+			continue
+		}
+
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HAR entry URL %q: %w", e.Request.URL, err)
+		}
+
+		body, err := e.Response.Content.Body()
+		if err != nil {
+			return nil, fmt.Errorf("invalid HAR entry content for %q: %w", e.Request.URL, err)
+		}
+
+		header := make(http.Header)
+		for name, values := range har.Header(e.Response.Headers) {
+			// Pseudo-headers are HTTP/2 framing, and HAR bodies are decoded
+			if strings.HasPrefix(name, ":") || name == "Content-Encoding" || !savedHeader(name) {
+				continue
+			}
+			header[name] = values
+		}
+
+		r := store.Record{
+			Method:      strings.ToUpper(e.Request.Method),
+			Path:        u.RequestURI(),
+			ContentType: e.Response.Content.MimeType,
+			Body:        body,
+			Status:      e.Response.Status,
+			Header:      header,
+			ModTime:     e.StartedDateTime,
+		}
+
+		k := [2]string{r.Method, r.Path}
+		if i, ok := index[k]; ok {
+			records[i].Steps = append(records[i].Steps, r)
+			continue
+		}
+		index[k] = len(records)
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+// recordsToHAR converts the records to an archive. The responses following
+// the first one in a sequence are exported as successive exchanges. The
+// URLs are resolved against base.
+func recordsToHAR(records []store.Record, base string) har.HAR {
+	archive := har.New(harCreator, "")
+
+	for _, r := range records {
+		for _, step := range append([]store.Record{r}, r.Steps...) {
+			status := step.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			archive.Log.Entries = append(archive.Log.Entries, har.Entry{
+				StartedDateTime: step.ModTime,
+				Request: har.Request{
+					Method:      r.Method,
+					URL:         base + r.Path,
+					HTTPVersion: "HTTP/1.1",
+					Cookies:     []har.Cookie{},
+					Headers:     []har.NameValue{},
+					QueryString: queryString(r.Path),
+					HeadersSize: -1,
+					BodySize:    0,
+				},
+				Response: har.Response{
+					Status:      status,
+					StatusText:  http.StatusText(status),
+					HTTPVersion: "HTTP/1.1",
+					Cookies:     []har.Cookie{},
+					Headers:     har.Headers(step.Header),
+					Content:     har.NewContent(step.ContentType, step.Body),
+					HeadersSize: -1,
+					BodySize:    len(step.Body),
+				},
+			})
+		}
+	}
+
+	return archive
+}
+
+// journalToHAR converts the recorded requests to an archive. The journal
+// does not keep the response headers and bodies: only the status codes are
+// exported. The URLs are resolved against base.
+func journalToHAR(entries []journalEntry, base string) har.HAR {
+	archive := har.New(harCreator, "")
+
+	for _, e := range entries {
+		// HAR has no encoding for the request bodies: the binary ones are
+		// left out, as well as the size of the truncated ones
+		var postData *har.PostData
+		if e.Body != "" && e.BodyEncoding == "" {
+			postData = &har.PostData{MimeType: e.Header.Get("Content-Type"), Text: e.Body}
+		}
+		bodySize := len(e.Body)
+		if e.BodyEncoding == "base64" {
+			body, _ := base64.StdEncoding.DecodeString(e.Body)
+			bodySize = len(body)
+		}
+		if e.BodyTruncated {
+			bodySize = -1
+		}
+
+		archive.Log.Entries = append(archive.Log.Entries, har.Entry{
+			StartedDateTime: e.Time,
+			Request: har.Request{
+				Method:      e.Method,
+				URL:         base + e.URL,
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []har.Cookie{},
+				Headers:     har.Headers(e.Header),
+				QueryString: queryString(e.URL),
+				PostData:    postData,
+				HeadersSize: -1,
+				BodySize:    bodySize,
+			},
+			Response: har.Response{
+				Status:      e.Status,
+				StatusText:  http.StatusText(e.Status),
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []har.Cookie{},
+				Headers:     []har.NameValue{},
+				Content:     har.Content{},
+				HeadersSize: -1,
+				BodySize:    -1,
+			},
+		})
+	}
+
+	return archive
+}
+
+// queryString lists the query string parameters of a request URI.
+func queryString(requestURI string) []har.NameValue {
+	params := []har.NameValue{}
+
+	i := strings.Index(requestURI, "?")
+	if i < 0 {
+		return params
+	}
+
+	for _, pair := range strings.Split(requestURI[i+1:], "&") {
+		if pair == "" {
+			continue
+		}
+		name, value := pair, ""
+		if j := strings.Index(pair, "="); j >= 0 {
+			name, value = pair[:j], pair[j+1:]
+		}
+		name, _ = url.QueryUnescape(name)
+		value, _ = url.QueryUnescape(value)
+		params = append(params, har.NameValue{Name: name, Value: value})
+	}
+
+	return params
+}
+
+// baseURL returns the scheme and host that the request was sent to.
+func baseURL(req *http.Request) string {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host
+}
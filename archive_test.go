@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pierreprinetti/apimock/har"
+	"github.com/pierreprinetti/apimock/store"
+)
+
+func TestRecordsFromHAR(t *testing.T) {
+	archive := har.New("browser", "1")
+	for _, e := range [...]struct {
+		method, url, body string
+		status            int
+	}{
+		{"get", "https://api.example.com/jobs/1?x=1", "pending", 200},
+		{"GET", "https://api.example.com/items", "items", 200},
+		{"GET", "https://api.example.com/jobs/1?x=1", "done", 201},
+	} {
+		archive.Log.Entries = append(archive.Log.Entries, har.Entry{
+			Request: har.Request{Method: e.method, URL: e.url},
+			Response: har.Response{
+				Status: e.status,
+				Headers: []har.NameValue{
+					{Name: ":status", Value: "200"},
+					{Name: "content-encoding", Value: "gzip"},
+					{Name: "cache-control", Value: "no-cache"},
+					{Name: "access-control-allow-origin", Value: "https://app.example.com"},
+					{Name: "access-control-allow-credentials", Value: "true"},
+					{Name: "connection", Value: "keep-alive"},
+					{Name: "upgrade", Value: "h2c"},
+				},
+				Content: har.NewContent("text/plain", []byte(e.body)),
+			},
+		})
+	}
+
+	records, err := recordsFromHAR(archive)
+	if err != nil {
+		t.Fatalf("converting: %v", err)
+	}
+
+	if want, have := 2, len(records); want != have {
+		t.Fatalf("expected %d records, found %d", want, have)
+	}
+
+	r := records[0]
+	if r.Method != "GET" || r.Path != "/jobs/1?x=1" || string(r.Body) != "pending" || r.ContentType != "text/plain" {
+		t.Errorf("unexpected record %+v", r)
+	}
+	if want, have := (http.Header{"Cache-Control": {"no-cache"}}), r.Header; !reflect.DeepEqual(want, have) {
+		t.Errorf("expected header %v, found %v", want, have)
+	}
+	if len(r.Steps) != 1 || string(r.Steps[0].Body) != "done" || r.Steps[0].Status != 201 {
+		t.Errorf("unexpected steps %+v", r.Steps)
+	}
+}
+
+func TestRecordsToHAR(t *testing.T) {
+	records := []store.Record{{
+		Method:      "GET",
+		Path:        "/jobs/1?x=1&y=a%20b",
+		ContentType: "text/plain",
+		Body:        []byte("pending"),
+		Header:      http.Header{"Cache-Control": {"no-cache"}},
+		ModTime:     time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Steps:       []store.Record{{Body: []byte("done"), Status: 201}},
+	}}
+
+	archive := recordsToHAR(records, "http://localhost:8800")
+
+	if want, have := 2, len(archive.Log.Entries); want != have {
+		t.Fatalf("expected %d entries, found %d", want, have)
+	}
+
+	e := archive.Log.Entries[0]
+	if want, have := "http://localhost:8800/jobs/1?x=1&y=a%20b", e.Request.URL; want != have {
+		t.Errorf("expected URL %q, found %q", want, have)
+	}
+	if want, have := []har.NameValue{{Name: "x", Value: "1"}, {Name: "y", Value: "a b"}}, e.Request.QueryString; !reflect.DeepEqual(want, have) {
+		t.Errorf("expected query string %v, found %v", want, have)
+	}
+	if e.Response.Status != 200 || e.Response.Content.Text != "pending" || !e.StartedDateTime.Equal(records[0].ModTime) {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if want, have := 201, archive.Log.Entries[1].Response.Status; want != have {
+		t.Errorf("expected status %d, found %d", want, have)
+	}
+
+	t.Run("round-trips", func(t *testing.T) {
+		data, err := json.Marshal(archive)
+		if err != nil {
+			t.Fatalf("encoding: %v", err)
+		}
+		var decoded har.HAR
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("decoding: %v", err)
+		}
+		back, err := recordsFromHAR(decoded)
+		if err != nil {
+			t.Fatalf("converting: %v", err)
+		}
+		if len(back) != 1 || back[0].Path != records[0].Path || len(back[0].Steps) != 1 {
+			t.Errorf("unexpected records %+v", back)
+		}
+	})
+}
+
+func TestJournalToHAR(t *testing.T) {
+	archive := journalToHAR([]journalEntry{{
+		Method: "POST",
+		URL:    "/orders",
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   `{"id":1}`,
+		Status: 201,
+	}}, "http://localhost:8800")
+
+	if want, have := 1, len(archive.Log.Entries); want != have {
+		t.Fatalf("expected %d entries, found %d", want, have)
+	}
+
+	e := archive.Log.Entries[0]
+	if e.Request.Method != "POST" || e.Request.URL != "http://localhost:8800/orders" || e.Response.Status != 201 {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if e.Request.PostData == nil || e.Request.PostData.Text != `{"id":1}` || e.Request.PostData.MimeType != "application/json" {
+		t.Errorf("unexpected post data %+v", e.Request.PostData)
+	}
+}
+
+func TestLoadHAR(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apimock")
+	if err != nil {
+		t.Fatalf("creating the temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "archive.har")
+	data, _ := json.Marshal(recordsToHAR([]store.Record{{Method: "GET", Path: "/a", Body: []byte("a")}}, "http://x"))
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		t.Fatalf("writing the archive: %v", err)
+	}
+
+	resources := store.New()
+	if err := loadHAR(resources, file); err != nil {
+		t.Fatalf("loading: %v", err)
+	}
+	if _, ok := resources.Record("GET", "/a"); !ok {
+		t.Error("expected the response to be saved")
+	}
+
+	if err := loadHAR(resources, filepath.Join(dir, "missing.har")); err == nil {
+		t.Error("expected an error loading a missing file")
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type faultKind int
+
+const (
+	faultError faultKind = iota
+	faultDrop
+	faultTruncate
+)
+
+// fault is a failure injected in a response with the given probability.
+type fault struct {
+	kind        faultKind
+	probability float64
+
+	// status is the status code of the error faults.
+	status int
+}
+
+// chaosRule holds the faults injected in the responses to the requests
+// whose path starts with prefix.
+type chaosRule struct {
+	prefix string
+	faults []fault
+}
+
+// parseChaos parses a semicolon-separated list of rules. Each rule is an
+// optional path prefix, followed by a comma-separated list of faults:
+//   - "error=p" or "error=p:status" responds with the given status code
+//     (500 by default) with probability p
+//   - "drop=p" closes the connection in the middle of the response body with
+//     probability p
+//   - "truncate=p" sends half of the response body with probability p
+//
+// For example: "error=0.1:503; /orders drop=0.5,truncate=0.2".
+// A rule without prefix applies to every path.
+func parseChaos(s string) ([]chaosRule, error) {
+	var rules []chaosRule
+	for _, r := range strings.Split(s, ";") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+
+		rule := chaosRule{prefix: "/"}
+		if strings.HasPrefix(r, "/") {
+			i := strings.IndexAny(r, " \t")
+			if i < 0 {
+				return nil, fmt.Errorf("invalid chaos rule %q: no faults", r)
+			}
+			rule.prefix, r = r[:i], strings.TrimSpace(r[i:])
+		}
+
+		for _, f := range strings.Split(r, ",") {
+			parsed, err := parseFault(strings.TrimSpace(f))
+			if err != nil {
+				return nil, fmt.Errorf("invalid chaos rule %q: %w", r, err)
+			}
+			rule.faults = append(rule.faults, parsed)
+		}
+
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseFault(s string) (fault, error) {
+	i := strings.Index(s, "=")
+	if i < 0 {
+		return fault{}, fmt.Errorf("invalid fault %q", s)
+	}
+	name, value := s[:i], s[i+1:]
+
+	var f fault
+	switch name {
+	case "error":
+		f.kind = faultError
+		f.status = http.StatusInternalServerError
+		if j := strings.Index(value, ":"); j >= 0 {
+			status, err := strconv.Atoi(value[j+1:])
+			if err != nil || status < 100 || status > 599 {
+				return fault{}, fmt.Errorf("invalid fault status %q", value[j+1:])
+			}
+			f.status, value = status, value[:j]
+		}
+	case "drop":
+		f.kind = faultDrop
+	case "truncate":
+		f.kind = faultTruncate
+	default:
+		return fault{}, fmt.Errorf("unknown fault %q", name)
+	}
+
+	p, err := strconv.ParseFloat(value, 64)
+	if err != nil || p < 0 || p > 1 {
+		return fault{}, fmt.Errorf("invalid fault probability %q", value)
+	}
+	f.probability = p
+
+	return f, nil
+}
+
+// Chaos is a middleware handler that randomly injects faults in the
+// responses.
+type Chaos struct {
+	next      http.Handler
+	resources peeker
+	rules     []chaosRule
+
+	// random returns a pseudo-random number in [0.0,1.0).
+	random func() float64
+}
+
+// newChaos returns a new Chaos instance
+func newChaos(next http.Handler, resources peeker, rules []chaosRule) Chaos {
+	return Chaos{
+		next:      next,
+		resources: resources,
+		rules:     rules,
+		random:    rand.Float64,
+	}
+}
+
+// rule returns the rule with the longest prefix matching the path.
+func (m Chaos) rule(path string) (chaosRule, bool) {
+	var (
+		best  chaosRule
+		found bool
+	)
+	for _, r := range m.rules {
+		if strings.HasPrefix(path, r.prefix) && (!found || len(r.prefix) > len(best.prefix)) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
+
+func (m Chaos) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	// Requests configuring apimock are spared, as well as pre-flight requests
+	if req.Method == http.MethodOptions || configures(m.resources, req) {
+		m.next.ServeHTTP(rw, req)
+		return
+	}
+
+	rule, ok := m.rule(req.URL.Path)
+	if !ok {
+		m.next.ServeHTTP(rw, req)
+		return
+	}
+
+	for _, f := range rule.faults {
+		if m.random() >= f.probability {
+			continue
+		}
+
+		switch f.kind {
+		case faultError:
+			http.Error(rw, http.StatusText(f.status), f.status)
+		case faultDrop:
+			res := buffer(m.next, req)
+			drop(rw, res)
+		case faultTruncate:
+			res := buffer(m.next, req)
+			res.body.Truncate(res.body.Len() / 2)
+			res.header.Set("Content-Length", strconv.Itoa(res.body.Len()))
+			res.send(rw)
+		}
+		return
+	}
+
+	m.next.ServeHTTP(rw, req)
+}
+
+// bufferWriter is a ResponseWriter that holds the response in memory.
+type bufferWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (bw *bufferWriter) Header() http.Header {
+	return bw.header
+}
+
+func (bw *bufferWriter) WriteHeader(code int) {
+	if bw.status == 0 {
+		bw.status = code
+	}
+}
+
+func (bw *bufferWriter) Write(b []byte) (int, error) {
+	if bw.status == 0 {
+		bw.status = http.StatusOK
+	}
+	return bw.body.Write(b)
+}
+
+// send copies the buffered response to rw.
+func (bw *bufferWriter) send(rw http.ResponseWriter) {
+	for k, v := range bw.header {
+		rw.Header()[k] = v
+	}
+	rw.WriteHeader(bw.status)
+	rw.Write(bw.body.Bytes())
+}
+
+// buffer serves the request to a bufferWriter.
+func buffer(h http.Handler, req *http.Request) *bufferWriter {
+	bw := &bufferWriter{header: make(http.Header)}
+	h.ServeHTTP(bw, req)
+	if bw.status == 0 {
+		bw.status = http.StatusOK
+	}
+	return bw
+}
+
+// drop announces the full response, then closes the connection after half
+// of its body.
+func drop(rw http.ResponseWriter, res *bufferWriter) {
+	full := res.body.Len()
+	res.header.Set("Content-Length", strconv.Itoa(full))
+	res.body.Truncate(full / 2)
+
+	hijacker, ok := rw.(http.Hijacker)
+	if !ok {
+		res.send(rw)
+		panic(http.ErrAbortHandler)
+	}
+
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		res.send(rw)
+		panic(http.ErrAbortHandler)
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(buf, "HTTP/1.1 %d %s\r\n", res.status, http.StatusText(res.status))
+	res.header.Write(buf)
+	buf.WriteString("\r\n")
+	buf.Write(res.body.Bytes())
+	buf.Flush()
+}
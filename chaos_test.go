@@ -0,0 +1,133 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pierreprinetti/apimock/store"
+)
+
+func TestParseChaos(t *testing.T) {
+	testCases := [...]struct {
+		value string
+		want  []chaosRule
+		ok    bool
+	}{
+		{"", nil, true},
+		{
+			"error=0.1",
+			[]chaosRule{{"/", []fault{{faultError, 0.1, 500}}}},
+			true,
+		},
+		{
+			"error=0.1:503; /orders drop=0.5,truncate=1",
+			[]chaosRule{
+				{"/", []fault{{faultError, 0.1, 503}}},
+				{"/orders", []fault{{kind: faultDrop, probability: 0.5}, {kind: faultTruncate, probability: 1}}},
+			},
+			true,
+		},
+		{"/orders", nil, false},
+		{"error", nil, false},
+		{"error=2", nil, false},
+		{"error=0.1:42", nil, false},
+		{"explode=0.1", nil, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			have, err := parseChaos(tc.value)
+			if ok := err == nil; ok != tc.ok {
+				t.Fatalf("expected ok %v, found error %v", tc.ok, err)
+			}
+			if tc.ok && !reflect.DeepEqual(have, tc.want) {
+				t.Errorf("expected %v, found %v", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestChaos(t *testing.T) {
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Length", "10")
+		rw.WriteHeader(http.StatusTeapot)
+		rw.Write([]byte("0123456789"))
+	})
+
+	newTestChaos := func(rules string, random float64, resources peeker) Chaos {
+		parsed, err := parseChaos(rules)
+		if err != nil {
+			t.Fatalf("parsing the rules: %v", err)
+		}
+		m := newChaos(next, resources, parsed)
+		m.random = func() float64 { return random }
+		return m
+	}
+
+	testCases := [...]struct {
+		name       string
+		rules      string
+		random     float64
+		method     string
+		path       string
+		header     http.Header
+		resources  *testrouter
+		wantStatus int
+		wantBody   string
+	}{
+		{"injects an error", "error=0.5:503", 0.2, "GET", "/a", nil, &testrouter{}, 503, "Service Unavailable\n"},
+		{"spares unlucky requests", "error=0.5:503", 0.7, "GET", "/a", nil, &testrouter{}, 418, "0123456789"},
+		{"truncates the body", "truncate=0.5", 0.2, "GET", "/a", nil, &testrouter{}, 418, "01234"},
+		{"prefers the longest prefix", "error=1; /a truncate=1", 0.2, "GET", "/a/b", nil, &testrouter{}, 418, "01234"},
+		{"ignores other paths", "/b error=1", 0.2, "GET", "/a", nil, &testrouter{}, 418, "0123456789"},
+		{"spares PUT requests saving a response", "error=1", 0.2, "PUT", "/a", nil, &testrouter{}, 418, "0123456789"},
+		{"spares configuration requests", "error=1", 0.2, "PUT", "/a", http.Header{store.MethodHeader: {"PUT"}}, &testrouter{method: "PUT", body: []byte("a")}, 418, "0123456789"},
+		{"faults mocked PUT requests", "error=1", 0.2, "PUT", "/a", nil, &testrouter{method: "PUT", body: []byte("a")}, 500, "Internal Server Error\n"},
+		{"spares pre-flight requests", "error=1", 0.2, "OPTIONS", "/a", nil, &testrouter{}, 418, "0123456789"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			for k, v := range tc.header {
+				req.Header[k] = v
+			}
+			newTestChaos(tc.rules, tc.random, tc.resources).ServeHTTP(rec, req)
+
+			if want, have := tc.wantStatus, rec.Code; want != have {
+				t.Errorf("expected status %d, found %d", want, have)
+			}
+			if want, have := tc.wantBody, rec.Body.String(); want != have {
+				t.Errorf("expected body %q, found %q", want, have)
+			}
+		})
+	}
+
+	t.Run("drops the connection", func(t *testing.T) {
+		srv := httptest.NewServer(newLogger(newTestChaos("drop=1", 0, &testrouter{})))
+		defer srv.Close()
+
+		res, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("calling GET: %v", err)
+		}
+		defer res.Body.Close()
+
+		if want, have := 418, res.StatusCode; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		if err == nil {
+			t.Errorf("expected an error reading the body, found none")
+		}
+		if want, have := "01234", string(body); !strings.HasPrefix(want, have) {
+			t.Errorf("expected a prefix of %q, found %q", want, have)
+		}
+	})
+}
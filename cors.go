@@ -1,6 +1,19 @@
 package main
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pierreprinetti/apimock/store"
+)
+
+// allowHeaders are the request headers that cross-origin scripts can send.
+var allowHeaders = strings.Join([]string{
+	"DNT", "X-CustomHeader", "Keep-Alive", "User-Agent", "X-Requested-With", "X-Api-Key",
+	"If-Modified-Since", "If-None-Match", "If-Match", "If-Unmodified-Since", "Range", "If-Range",
+	"Cache-Control", "Content-Type",
+	store.StatusHeader, store.MethodHeader, store.QueryHeader, store.LatencyHeader, store.BandwidthHeader, store.SequenceHeader,
+}, ",")
 
 // Cors is a middleware handler that adds Cross-Origin-Resource-Sharing headers.
 type Cors struct {
@@ -19,7 +32,13 @@ func (m Cors) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Max-Age", "1728000") // Pre-flight info is valid for 20 days
 	}
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, POST, DELETE, HEAD, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "DNT,X-CustomHeader,Keep-Alive,User-Agent,X-Requested-With,X-Api-Key,If-Modified-Since,Cache-Control,Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, POST, PATCH, DELETE, HEAD, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+	// The names of the saved response headers are arbitrary: allow whatever
+	// the pre-flight request asks for
+	if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		w.Header().Set("Access-Control-Allow-Headers", requested)
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+	}
 	m.next.ServeHTTP(w, r)
 }
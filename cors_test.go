@@ -45,8 +45,8 @@ func TestNewCors(t *testing.T) {
 
 		for k, v := range map[string]string{
 			"Access-Control-Allow-Origin":  "*",
-			"Access-Control-Allow-Methods": "GET, PUT, POST, DELETE, HEAD, OPTIONS",
-			"Access-Control-Allow-Headers": "DNT,X-CustomHeader,Keep-Alive,User-Agent,X-Requested-With,X-Api-Key,If-Modified-Since,Cache-Control,Content-Type",
+			"Access-Control-Allow-Methods": "GET, PUT, POST, PATCH, DELETE, HEAD, OPTIONS",
+			"Access-Control-Allow-Headers": "DNT,X-CustomHeader,Keep-Alive,User-Agent,X-Requested-With,X-Api-Key,If-Modified-Since,If-None-Match,If-Match,If-Unmodified-Since,Range,If-Range,Cache-Control,Content-Type,X-Apimock-Status,X-Apimock-Method,X-Apimock-Query,X-Apimock-Latency,X-Apimock-Bandwidth,X-Apimock-Sequence",
 		} {
 			if want, have := v, rw.HeaderMap.Get(k); want != have {
 				t.Errorf("expected header %q to have value %q, found %q", k, want, have)
@@ -68,4 +68,20 @@ func TestNewCors(t *testing.T) {
 			t.Errorf("expected header %q to have value %q, found %q", key, want, have)
 		}
 	})
+
+	t.Run("requested headers are allowed in OPTIONS calls", func(t *testing.T) {
+		var h testhandler
+		wrapped := newCors(&h)
+
+		req, _ := http.NewRequest("OPTIONS", "/", strings.NewReader(""))
+		req.Header.Set("Access-Control-Request-Headers", "x-apimock-status,x-apimock-header-x-total-count")
+		rw := httptest.NewRecorder()
+
+		wrapped.ServeHTTP(rw, req)
+
+		key := "Access-Control-Allow-Headers"
+		if want, have := "x-apimock-status,x-apimock-header-x-total-count", rw.HeaderMap.Get(key); want != have {
+			t.Errorf("expected header %q to have value %q, found %q", key, want, have)
+		}
+	})
 }
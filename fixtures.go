@@ -0,0 +1,85 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fixtureKey returns the request path that a file in the fixtures directory
+// is served at: the file path relative to dir, without extension.
+func fixtureKey(dir, file string) (string, error) {
+	rel, err := filepath.Rel(dir, file)
+	if err != nil {
+		return "", err
+	}
+
+	rel = strings.TrimSuffix(rel, filepath.Ext(rel))
+
+	u := url.URL{Path: "/" + filepath.ToSlash(rel)}
+	return u.EscapedPath(), nil
+}
+
+// isHidden reports whether the file or directory name starts with a dot.
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// loadFixture saves the content of the file in the store. The content type
+// is derived from the file extension.
+func loadFixture(resources router, key, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	req, err := http.NewRequest(http.MethodPut, key, f)
+	if err != nil {
+		return err
+	}
+
+	if contentType := mime.TypeByExtension(filepath.Ext(file)); contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	return resources.Set(http.MethodGet, key, req)
+}
+
+// walkFixtures calls fn for every file in the directory tree, along with the
+// key it is served at. Hidden files and directories are skipped.
+func walkFixtures(dir string, fn func(key, file string, info os.FileInfo) error) error {
+	return filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if file != dir && isHidden(info.Name()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		key, err := fixtureKey(dir, file)
+		if err != nil {
+			return err
+		}
+
+		return fn(key, file, info)
+	})
+}
+
+// loadFixtures saves every file of the directory tree in the store.
+func loadFixtures(resources router, dir string) error {
+	return walkFixtures(dir, func(key, file string, _ os.FileInfo) error {
+		return loadFixture(resources, key, file)
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pierreprinetti/apimock/store"
+)
+
+// writeFixtures creates the files in a new temporary directory, and returns
+// its path.
+func writeFixtures(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "apimock")
+	if err != nil {
+		t.Fatalf("creating the temporary directory: %v", err)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("creating the fixture directory: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing the fixture: %v", err)
+		}
+	}
+
+	return dir
+}
+
+func TestFixtureKey(t *testing.T) {
+	tests := [...]struct {
+		file string
+		want string
+	}{
+		{"users/42.json", "/users/42"},
+		{"users.json", "/users"},
+		{"users/42", "/users/42"},
+		{"my files/a b.txt", "/my%20files/a%20b"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.file, func(t *testing.T) {
+			have, err := fixtureKey("fixtures", filepath.Join("fixtures", filepath.FromSlash(tc.file)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if have != tc.want {
+				t.Errorf("expected key %q, found %q", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"users/42.json":   `{"id": 42}`,
+		"readme":          "hello",
+		".hidden/1.json":  "{}",
+		"users/.DS_Store": "",
+	})
+	defer os.RemoveAll(dir)
+
+	resources := store.New(store.WithDefaultContentType("default/type"))
+	if err := loadFixtures(resources, dir); err != nil {
+		t.Fatalf("loading the fixtures: %v", err)
+	}
+
+	for key, want := range map[string]struct {
+		body        string
+		contentType string
+	}{
+		"/users/42": {`{"id": 42}`, "application/json"},
+		"/readme":   {"hello", "default/type"},
+	} {
+		h, ok := resources.Get(http.MethodGet, key)
+		if !ok {
+			t.Errorf("expected entry %q", key)
+			continue
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", key, nil))
+		if have := rec.Body.String(); have != want.body {
+			t.Errorf("expected body %q for %q, found %q", want.body, key, have)
+		}
+		if have := rec.Header().Get("Content-Type"); have != want.contentType {
+			t.Errorf("expected content type %q for %q, found %q", want.contentType, key, have)
+		}
+	}
+
+	for _, key := range []string{"/.hidden/1", "/users/"} {
+		if _, ok := resources.Get(http.MethodGet, key); ok {
+			t.Errorf("unexpected entry %q", key)
+		}
+	}
+}
@@ -1,22 +1,111 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"strings"
+
+	"github.com/pierreprinetti/apimock/store"
 )
 
 type router interface {
-	Get(string) (http.Handler, bool)
-	Set(string, *http.Request) error
-	Del(string) bool
+	Get(string, string) (http.Handler, bool)
+	Peek(string, string) (http.Handler, bool)
+	Echo(string, string) (http.Handler, bool)
+	List(string) (http.Handler, bool)
+	Set(string, string, *http.Request) error
+	Add(string, *http.Request) (string, error)
+	Patch(string, *http.Request) (bool, error)
+	Del(string, string, *http.Request) (bool, error)
+	Rewind(string, string) bool
+}
+
+// targetMethod returns the method whose response is configured by a PUT or
+// DELETE request: the value of the X-Apimock-Method header, or GET.
+func targetMethod(req *http.Request) string {
+	if method := req.Header.Get(store.MethodHeader); method != "" {
+		return strings.ToUpper(method)
+	}
+	return http.MethodGet
+}
+
+// isConfiguration reports whether the request configures the response of
+// another method or a sequence of responses, rather than being a request to
+// mock.
+func isConfiguration(req *http.Request) bool {
+	return (req.Method == http.MethodPut || req.Method == http.MethodDelete) &&
+		(req.Header.Get(store.MethodHeader) != "" || req.Header.Get(store.SequenceHeader) != "")
+}
+
+type peeker interface {
+	Peek(string, string) (http.Handler, bool)
+}
+
+// configures reports whether the request configures apimock: a
+// configuration request, or a PUT or DELETE request without a response saved
+// for its method.
+func configures(resources peeker, req *http.Request) bool {
+	if isConfiguration(req) {
+		return true
+	}
+	if req.Method != http.MethodPut && req.Method != http.MethodDelete {
+		return false
+	}
+	_, ok := resources.Peek(req.Method, req.URL.String())
+	return !ok
+}
+
+// savedHandler answers with the response saved for the request method, if
+// any. GET and OPTIONS requests, and configuration requests, are left to
+// the other handlers.
+// The returned boolean is true if the request has been answered.
+func savedHandler(resources router, rw http.ResponseWriter, req *http.Request) bool {
+	if req.Method == http.MethodGet || req.Method == http.MethodOptions || isConfiguration(req) {
+		return false
+	}
+
+	e, ok := resources.Get(req.Method, req.URL.String())
+	if !ok {
+		return false
+	}
+
+	e.ServeHTTP(rw, req)
+	return true
+}
+
+// getHandler answers with the saved response, or with the list of the
+// saved responses below the requested path. Other requests are passed to the
+// fallback handler, or answered with 404 if it is nil.
+func getHandler(resources router, fallback http.Handler) http.HandlerFunc {
+	return lookupHandler(resources.Get, resources, fallback)
 }
 
-func getHandler(resources router) http.HandlerFunc {
+// headHandler answers like getHandler, without a body. The saved response is
+// looked up with Peek, so that HEAD requests don't advance the sequences.
+func headHandler(resources router, fallback http.Handler) http.HandlerFunc {
+	peek := lookupHandler(resources.Peek, resources, fallback)
+
+	return func(rw http.ResponseWriter, req *http.Request) {
+		peek(&headWriter{ResponseWriter: rw}, req)
+	}
+}
+
+// lookupHandler answers with the GET response returned by lookup, or with
+// the list of the saved responses below the requested path.
+func lookupHandler(lookup func(string, string) (http.Handler, bool), resources router, fallback http.Handler) http.HandlerFunc {
 	return func(rw http.ResponseWriter, req *http.Request) {
 		path := req.URL.String()
-		e, ok := resources.Get(path)
+		e, ok := lookup(http.MethodGet, path)
+		if !ok {
+			e, ok = resources.List(req.URL.EscapedPath())
+		}
 
 		if !ok {
+			if fallback != nil {
+				fallback.ServeHTTP(rw, req)
+				return
+			}
 			rw.WriteHeader(http.StatusNotFound)
 			return
 		}
@@ -28,11 +117,50 @@ func getHandler(resources router) http.HandlerFunc {
 func putHandler(resources router) http.HandlerFunc {
 	return func(rw http.ResponseWriter, req *http.Request) {
 		path := req.URL.String()
-		if err := resources.Set(path, req); err != nil {
-			log.Panic(err)
+		method := targetMethod(req)
+		if err := resources.Set(method, path, req); err != nil {
+			storeFailed(rw, err)
+			return
+		}
+
+		e, _ := resources.Echo(method, path)
+
+		e.ServeHTTP(rw, req)
+	}
+}
+
+func postHandler(resources router) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		path, err := resources.Add(req.URL.EscapedPath(), req)
+		if err != nil {
+			storeFailed(rw, err)
+			return
+		}
+
+		e, _ := resources.Echo(http.MethodGet, path)
+
+		rw.Header().Set("Location", path)
+		rw.Header().Set("Access-Control-Expose-Headers", "Location")
+		e.ServeHTTP(&statusWriter{ResponseWriter: rw, status: http.StatusCreated}, req)
+	}
+}
+
+func patchHandler(resources router) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		path := req.URL.String()
+
+		ok, err := resources.Patch(path, req)
+		if err != nil {
+			storeFailed(rw, err)
+			return
+		}
+
+		if !ok {
+			rw.WriteHeader(http.StatusNotFound)
+			return
 		}
 
-		e, _ := resources.Get(path)
+		e, _ := resources.Echo(http.MethodGet, path)
 
 		e.ServeHTTP(rw, req)
 	}
@@ -42,7 +170,22 @@ func deleteHandler(resources router) http.HandlerFunc {
 	return func(rw http.ResponseWriter, req *http.Request) {
 		path := req.URL.String()
 
-		if ok := resources.Del(path); !ok {
+		if req.Header.Get(store.SequenceHeader) == "rewind" {
+			if !resources.Rewind(targetMethod(req), path) {
+				rw.WriteHeader(http.StatusNotFound)
+				return
+			}
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		ok, err := resources.Del(targetMethod(req), path, req)
+		if err != nil {
+			storeFailed(rw, err)
+			return
+		}
+
+		if !ok {
 			rw.WriteHeader(http.StatusNotFound)
 			return
 		}
@@ -51,6 +194,66 @@ func deleteHandler(resources router) http.HandlerFunc {
 	}
 }
 
+// storeFailed answers with a client error status if the store rejected the
+// request. Any other error is unexpected, and makes the handler panic.
+func storeFailed(rw http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrInvalidHeader), errors.Is(err, store.ErrInvalidPatch), errors.Is(err, store.ErrInvalidRecord):
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, store.ErrUnsupportedPatch):
+		rw.Header().Set("Accept-Patch", store.MergePatchType+", "+store.JSONPatchType)
+		http.Error(rw, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, store.ErrPatchConflict):
+		http.Error(rw, err.Error(), http.StatusConflict)
+	case errors.Is(err, store.ErrPreconditionFailed):
+		http.Error(rw, err.Error(), http.StatusPreconditionFailed)
+	default:
+		log.Panic(err)
+	}
+}
+
 func optionsHandler(rw http.ResponseWriter, _ *http.Request) {
 	rw.WriteHeader(http.StatusNoContent)
 }
+
+// statusWriter sends its own status code in place of the one set by the
+// wrapped handler.
+type statusWriter struct {
+	http.ResponseWriter
+
+	status      int
+	wroteHeader bool
+}
+
+func (sw *statusWriter) WriteHeader(_ int) {
+	if sw.wroteHeader {
+		return
+	}
+	sw.wroteHeader = true
+	sw.ResponseWriter.WriteHeader(sw.status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	sw.WriteHeader(sw.status)
+	return sw.ResponseWriter.Write(b)
+}
+
+// headWriter discards the response body, leaving the headers untouched.
+type headWriter struct {
+	http.ResponseWriter
+
+	wroteHeader bool
+}
+
+func (hw *headWriter) WriteHeader(code int) {
+	if hw.wroteHeader {
+		return
+	}
+	hw.wroteHeader = true
+	hw.ResponseWriter.WriteHeader(code)
+}
+
+func (hw *headWriter) Write(b []byte) (int, error) {
+	hw.WriteHeader(http.StatusOK)
+	return len(b), nil
+}
@@ -7,17 +7,31 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/pierreprinetti/apimock/store"
 )
 
 type testrouter struct {
+	method           string
 	path             string
 	body             []byte
+	addCalledWith    string
+	list             []byte
+	setErr           error
+	patchCalledWith  string
+	patchErr         error
 	deleteCalledWith string
+	deleteMethod     string
 	deleteBool       bool
+	rewindCalledWith string
 }
 
-func (tr *testrouter) Get(_ string) (http.Handler, bool) {
-	if len(tr.body) == 0 {
+func (tr *testrouter) Get(method, _ string) (http.Handler, bool) {
+	saved := tr.method
+	if saved == "" {
+		saved = http.MethodGet
+	}
+	if len(tr.body) == 0 || method != saved {
 		return nil, false
 	}
 	h := func(rw http.ResponseWriter, _ *http.Request) {
@@ -26,15 +40,61 @@ func (tr *testrouter) Get(_ string) (http.Handler, bool) {
 	return http.HandlerFunc(h), true
 }
 
-func (tr *testrouter) Set(path string, req *http.Request) error {
+func (tr *testrouter) Peek(method, path string) (http.Handler, bool) {
+	return tr.Get(method, path)
+}
+
+func (tr *testrouter) Echo(method, path string) (http.Handler, bool) {
+	return tr.Get(method, path)
+}
+
+func (tr *testrouter) List(_ string) (http.Handler, bool) {
+	if len(tr.list) == 0 {
+		return nil, false
+	}
+	h := func(rw http.ResponseWriter, _ *http.Request) {
+		rw.Write(tr.list)
+	}
+	return http.HandlerFunc(h), true
+}
+
+func (tr *testrouter) Set(method, path string, req *http.Request) error {
+	if tr.setErr != nil {
+		return tr.setErr
+	}
 	var err error
 	tr.body, err = ioutil.ReadAll(req.Body)
+	tr.method = method
 	tr.path = path
 	return err
 }
 
-func (tr *testrouter) Del(path string) bool {
+func (tr *testrouter) Add(collection string, req *http.Request) (string, error) {
+	var err error
+	tr.body, err = ioutil.ReadAll(req.Body)
+	tr.addCalledWith = collection
+	tr.path = collection + "/1"
+	return tr.path, err
+}
+
+func (tr *testrouter) Patch(path string, req *http.Request) (bool, error) {
+	tr.patchCalledWith = path
+	if tr.patchErr != nil || len(tr.body) == 0 {
+		return len(tr.body) > 0, tr.patchErr
+	}
+	patch, err := ioutil.ReadAll(req.Body)
+	tr.body = append(tr.body, patch...)
+	return true, err
+}
+
+func (tr *testrouter) Del(method, path string, _ *http.Request) (bool, error) {
+	tr.deleteMethod = method
 	tr.deleteCalledWith = path
+	return tr.deleteBool, nil
+}
+
+func (tr *testrouter) Rewind(_, path string) bool {
+	tr.rewindCalledWith = path
 	return tr.deleteBool
 }
 
@@ -76,6 +136,21 @@ func TestGetHandler(t *testing.T) {
 				hasContents("hey"),
 			),
 		},
+		{
+			"lists the collection",
+			&testrouter{list: []byte(`["hey"]`)},
+			check(
+				hasStatus(200),
+				hasContents(`["hey"]`),
+			),
+		},
+		{
+			"prefers the entry to the collection",
+			&testrouter{body: []byte("hey"), list: []byte(`["hey"]`)},
+			check(
+				hasContents("hey"),
+			),
+		},
 		{
 			"miss is 404",
 			&testrouter{},
@@ -88,7 +163,7 @@ func TestGetHandler(t *testing.T) {
 	req, _ := http.NewRequest("GET", "http://foo.com/", strings.NewReader(""))
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			h := getHandler(tc.store)
+			h := getHandler(tc.store, nil)
 			rec := httptest.NewRecorder()
 			h(rec, req)
 			for _, check := range tc.checks {
@@ -100,6 +175,50 @@ func TestGetHandler(t *testing.T) {
 	}
 }
 
+func TestHeadHandler(t *testing.T) {
+	t.Run("sends no body", func(t *testing.T) {
+		req, _ := http.NewRequest("HEAD", "/wow", nil)
+		rec := httptest.NewRecorder()
+		headHandler(&testrouter{body: []byte("hey")}, nil)(rec, req)
+		if want, have := 200, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+		if have := rec.Body.String(); have != "" {
+			t.Errorf("expected no body, found %q", have)
+		}
+	})
+
+	t.Run("miss is 404", func(t *testing.T) {
+		req, _ := http.NewRequest("HEAD", "/wow", nil)
+		rec := httptest.NewRecorder()
+		headHandler(&testrouter{}, nil)(rec, req)
+		if want, have := 404, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+	})
+
+	t.Run("does not advance sequences", func(t *testing.T) {
+		resources := store.New()
+		for _, step := range [...]struct{ body, sequence string }{{"first", ""}, {"second", "last"}} {
+			req, _ := http.NewRequest("PUT", "/wow", strings.NewReader(step.body))
+			if step.sequence != "" {
+				req.Header.Set(store.SequenceHeader, step.sequence)
+			}
+			putHandler(resources)(httptest.NewRecorder(), req)
+		}
+
+		req, _ := http.NewRequest("HEAD", "/wow", nil)
+		headHandler(resources, nil)(httptest.NewRecorder(), req)
+
+		req, _ = http.NewRequest("GET", "/wow", nil)
+		rec := httptest.NewRecorder()
+		getHandler(resources, nil)(rec, req)
+		if want, have := "first", rec.Body.String(); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+	})
+}
+
 func TestPutHandler(t *testing.T) {
 	type checkFunc func(*testrouter, *httptest.ResponseRecorder) error
 	check := func(fns ...checkFunc) []checkFunc { return fns }
@@ -177,9 +296,44 @@ func TestPutHandler(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("stores the response of another method", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/login", strings.NewReader("token"))
+		req.Header.Set(store.MethodHeader, "post")
+		store := &testrouter{}
+		h := putHandler(store)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if want, have := "POST", store.method; want != have {
+			t.Errorf("expected method %q, found %q", want, have)
+		}
+		if want, have := "token", rec.Body.String(); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+	})
+
+	t.Run("rejects failed preconditions", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/wow", strings.NewReader(""))
+		h := putHandler(&testrouter{setErr: fmt.Errorf("wrapped: %w", store.ErrPreconditionFailed)})
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if want, have := 412, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+	})
+
+	t.Run("rejects invalid headers", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/wow", strings.NewReader(""))
+		h := putHandler(&testrouter{setErr: fmt.Errorf("wrapped: %w", store.ErrInvalidHeader)})
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if want, have := 400, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+	})
 }
 
-func TestDeleteHandler(t *testing.T) {
+func TestPostHandler(t *testing.T) {
 	type checkFunc func(*testrouter, *httptest.ResponseRecorder) error
 	check := func(fns ...checkFunc) []checkFunc { return fns }
 
@@ -191,10 +345,26 @@ func TestDeleteHandler(t *testing.T) {
 			return nil
 		}
 	}
-	deleteCalledWith := func(want string) checkFunc {
+	responseHasLocation := func(want string) checkFunc {
+		return func(_ *testrouter, rec *httptest.ResponseRecorder) error {
+			if have := rec.Header().Get("Location"); have != want {
+				return fmt.Errorf("expected location %q, found %q", want, have)
+			}
+			return nil
+		}
+	}
+	responseHasContents := func(want string) checkFunc {
+		return func(_ *testrouter, rec *httptest.ResponseRecorder) error {
+			if have := rec.Body.String(); have != want {
+				return fmt.Errorf("expected body %q, found %q", want, have)
+			}
+			return nil
+		}
+	}
+	addCalledWith := func(want string) checkFunc {
 		return func(router *testrouter, _ *httptest.ResponseRecorder) error {
-			if have := router.deleteCalledWith; have != want {
-				return fmt.Errorf("expected Del called with path %q, found %q", want, have)
+			if have := router.addCalledWith; have != want {
+				return fmt.Errorf("expected Add called with collection %q, found %q", want, have)
 			}
 			return nil
 		}
@@ -203,32 +373,264 @@ func TestDeleteHandler(t *testing.T) {
 	tests := [...]struct {
 		name   string
 		path   string
+		body   string
+		checks []checkFunc
+	}{
+		{
+			"adds to the collection",
+			"/items",
+			`{"content": "NEW!"}`,
+			check(
+				addCalledWith("/items"),
+			),
+		},
+		{
+			"ignores the query string",
+			"/items?page=2",
+			`{"content": "NEW!"}`,
+			check(
+				addCalledWith("/items"),
+			),
+		},
+		{
+			"returns the newly created entry",
+			"/items",
+			`{"content": "NEW!"}`,
+			check(
+				responseHasStatus(201),
+				responseHasLocation("/items/1"),
+				responseHasContents(`{"content": "NEW!"}`),
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", tc.path, strings.NewReader(tc.body))
+			store := &testrouter{}
+			h := postHandler(store)
+			rec := httptest.NewRecorder()
+			h(rec, req)
+			for _, check := range tc.checks {
+				if err := check(store, rec); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+
+	t.Run("exposes the location", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/items", strings.NewReader(`{"content": "NEW!"}`))
+		rec := httptest.NewRecorder()
+		postHandler(store.New())(rec, req)
+
+		if want, have := "Content-Range, ETag, Location", rec.Header().Get("Access-Control-Expose-Headers"); want != have {
+			t.Errorf("expected exposed headers %q, found %q", want, have)
+		}
+	})
+}
+
+func TestPatchHandler(t *testing.T) {
+	type checkFunc func(*testrouter, *httptest.ResponseRecorder) error
+	check := func(fns ...checkFunc) []checkFunc { return fns }
+
+	responseHasStatus := func(want int) checkFunc {
+		return func(_ *testrouter, rec *httptest.ResponseRecorder) error {
+			if rec.Code != want {
+				return fmt.Errorf("expected status %d, found %d", want, rec.Code)
+			}
+			return nil
+		}
+	}
+	responseHasContents := func(want string) checkFunc {
+		return func(_ *testrouter, rec *httptest.ResponseRecorder) error {
+			if have := rec.Body.String(); have != want {
+				return fmt.Errorf("expected body %q, found %q", want, have)
+			}
+			return nil
+		}
+	}
+	responseHasHeader := func(name, want string) checkFunc {
+		return func(_ *testrouter, rec *httptest.ResponseRecorder) error {
+			if have := rec.Header().Get(name); have != want {
+				return fmt.Errorf("expected header %s %q, found %q", name, want, have)
+			}
+			return nil
+		}
+	}
+	patchCalledWith := func(want string) checkFunc {
+		return func(router *testrouter, _ *httptest.ResponseRecorder) error {
+			if have := router.patchCalledWith; have != want {
+				return fmt.Errorf("expected Patch called with path %q, found %q", want, have)
+			}
+			return nil
+		}
+	}
+
+	tests := [...]struct {
+		name   string
 		store  *testrouter
 		checks []checkFunc
+	}{
+		{
+			"returns the patched entry",
+			&testrouter{body: []byte("saved")},
+			check(
+				patchCalledWith("/wow"),
+				responseHasStatus(200),
+				responseHasContents("saved+patch"),
+			),
+		},
+		{
+			"returns 404 for unknown routes",
+			&testrouter{},
+			check(
+				responseHasStatus(404),
+			),
+		},
+		{
+			"returns 400 for invalid patches",
+			&testrouter{body: []byte("saved"), patchErr: store.ErrInvalidPatch},
+			check(
+				responseHasStatus(400),
+			),
+		},
+		{
+			"returns 409 for conflicts",
+			&testrouter{body: []byte("saved"), patchErr: store.ErrPatchConflict},
+			check(
+				responseHasStatus(409),
+			),
+		},
+		{
+			"returns 415 for unsupported patches",
+			&testrouter{body: []byte("saved"), patchErr: store.ErrUnsupportedPatch},
+			check(
+				responseHasStatus(415),
+				responseHasHeader("Accept-Patch", "application/merge-patch+json, application/json-patch+json"),
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("PATCH", "/wow", strings.NewReader("+patch"))
+			h := patchHandler(tc.store)
+			rec := httptest.NewRecorder()
+			h(rec, req)
+			for _, check := range tc.checks {
+				if err := check(tc.store, rec); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteHandler(t *testing.T) {
+	type checkFunc func(*testrouter, *httptest.ResponseRecorder) error
+	check := func(fns ...checkFunc) []checkFunc { return fns }
+
+	responseHasStatus := func(want int) checkFunc {
+		return func(_ *testrouter, rec *httptest.ResponseRecorder) error {
+			if rec.Code != want {
+				return fmt.Errorf("expected status %d, found %d", want, rec.Code)
+			}
+			return nil
+		}
+	}
+	deleteCalledWith := func(want string) checkFunc {
+		return func(router *testrouter, _ *httptest.ResponseRecorder) error {
+			if have := router.deleteCalledWith; have != want {
+				return fmt.Errorf("expected Del called with path %q, found %q", want, have)
+			}
+			return nil
+		}
+	}
+
+	deleteMethodIs := func(want string) checkFunc {
+		return func(router *testrouter, _ *httptest.ResponseRecorder) error {
+			if have := router.deleteMethod; have != want {
+				return fmt.Errorf("expected Del called with method %q, found %q", want, have)
+			}
+			return nil
+		}
+	}
+
+	rewindCalledWith := func(want string) checkFunc {
+		return func(router *testrouter, _ *httptest.ResponseRecorder) error {
+			if have := router.rewindCalledWith; have != want {
+				return fmt.Errorf("expected Rewind called with path %q, found %q", want, have)
+			}
+			return nil
+		}
+	}
+
+	tests := [...]struct {
+		name     string
+		path     string
+		sequence string
+		store    *testrouter
+		checks   []checkFunc
 	}{
 		{
 			"deletes an entry",
 			"/wow",
+			"",
 			&testrouter{deleteBool: true},
 			check(
 				deleteCalledWith("/wow"),
 				responseHasStatus(204),
 			),
 		},
+		{
+			"deletes the GET response by default",
+			"/wow",
+			"",
+			&testrouter{deleteBool: true},
+			check(
+				deleteMethodIs("GET"),
+			),
+		},
 		{
 			"returns 404 for unknown routes",
 			"/wow",
+			"",
 			&testrouter{deleteBool: false},
 			check(
 				deleteCalledWith("/wow"),
 				responseHasStatus(404),
 			),
 		},
+		{
+			"rewinds a sequence",
+			"/wow",
+			"rewind",
+			&testrouter{deleteBool: true},
+			check(
+				rewindCalledWith("/wow"),
+				deleteCalledWith(""),
+				responseHasStatus(204),
+			),
+		},
+		{
+			"returns 404 rewinding unknown routes",
+			"/wow",
+			"rewind",
+			&testrouter{deleteBool: false},
+			check(
+				rewindCalledWith("/wow"),
+				responseHasStatus(404),
+			),
+		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			req, _ := http.NewRequest("DELETE", tc.path, strings.NewReader(""))
+			if tc.sequence != "" {
+				req.Header.Set(store.SequenceHeader, tc.sequence)
+			}
 			h := deleteHandler(tc.store)
 			rec := httptest.NewRecorder()
 			h(rec, req)
@@ -241,6 +643,70 @@ func TestDeleteHandler(t *testing.T) {
 	}
 }
 
+func TestSavedHandler(t *testing.T) {
+	tests := [...]struct {
+		name   string
+		method string
+		header string
+		store  *testrouter
+		want   bool
+	}{
+		{
+			"answers with the saved response",
+			"POST",
+			"",
+			&testrouter{method: "POST", body: []byte("saved")},
+			true,
+		},
+		{
+			"ignores the other methods",
+			"PATCH",
+			"",
+			&testrouter{method: "POST", body: []byte("saved")},
+			false,
+		},
+		{
+			"leaves GET to the GET handler",
+			"GET",
+			"",
+			&testrouter{body: []byte("saved")},
+			false,
+		},
+		{
+			"leaves configuration requests alone",
+			"PUT",
+			"PUT",
+			&testrouter{method: "PUT", body: []byte("saved")},
+			false,
+		},
+		{
+			"answers PUT requests without the method header",
+			"PUT",
+			"",
+			&testrouter{method: "PUT", body: []byte("saved")},
+			true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tc.method, "/rpc", strings.NewReader(""))
+			if tc.header != "" {
+				req.Header.Set(store.MethodHeader, tc.header)
+			}
+			rec := httptest.NewRecorder()
+			if have := savedHandler(tc.store, rec, req); have != tc.want {
+				t.Errorf("expected %v, found %v", tc.want, have)
+			}
+			if tc.want {
+				if want, have := "saved", rec.Body.String(); want != have {
+					t.Errorf("expected body %q, found %q", want, have)
+				}
+			}
+		})
+	}
+}
+
 func TestOptionsHandler(t *testing.T) {
 	t.Run("returns 204", func(t *testing.T) {
 		req, _ := http.NewRequest("OPTIONS", "/", strings.NewReader(""))
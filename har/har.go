@@ -0,0 +1,165 @@
+// Package har implements the subset of the HTTP Archive (HAR) 1.2 format
+// that is needed to save and replay HTTP exchanges.
+//
+// See http://www.softwareishard.com/blog/har-12-spec/
+package har
+
+import (
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+// Version is the version of the HAR format written by this package.
+const Version = "1.2"
+
+// HAR is the root of an HTTP Archive.
+type HAR struct {
+	Log Log `json:"log"`
+}
+
+// Log holds the exchanges of an archive.
+type Log struct {
+	Version string  `json:"version"`
+	Creator Creator `json:"creator"`
+	Entries []Entry `json:"entries"`
+}
+
+// Creator identifies the application that created the archive.
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Entry is an HTTP exchange.
+type Entry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+
+	// Time is the total duration of the exchange, in milliseconds.
+	Time float64 `json:"time"`
+
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+	Cache    struct{} `json:"cache"`
+	Timings  Timings  `json:"timings"`
+}
+
+// Request is the request of an exchange.
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	PostData    *PostData   `json:"postData,omitempty"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+// Response is the response of an exchange.
+type Response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	Content     Content     `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+// Cookie is a cookie sent or received in an exchange.
+type Cookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NameValue is a header or a query string parameter.
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PostData is the body of a request.
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// Content is the body of a response. The Text is either the body itself, or
+// its base64 encoding if the Encoding is "base64".
+type Content struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Timings is the duration of the phases of an exchange, in milliseconds.
+type Timings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// New returns an empty archive created by the named application.
+func New(name, version string) HAR {
+	return HAR{
+		Log: Log{
+			Version: Version,
+			Creator: Creator{Name: name, Version: version},
+			Entries: []Entry{},
+		},
+	}
+}
+
+// NewContent returns the Content of a response body. Bodies that are not
+// valid UTF-8 are encoded in base64.
+func NewContent(mimeType string, body []byte) Content {
+	c := Content{
+		Size:     len(body),
+		MimeType: mimeType,
+	}
+
+	if utf8.Valid(body) {
+		c.Text = string(body)
+	} else {
+		c.Text = base64.StdEncoding.EncodeToString(body)
+		c.Encoding = "base64"
+	}
+
+	return c
+}
+
+// Body returns the decoded response body.
+func (c Content) Body() ([]byte, error) {
+	if c.Encoding == "base64" {
+		return base64.StdEncoding.DecodeString(c.Text)
+	}
+	return []byte(c.Text), nil
+}
+
+// Headers returns the headers of h, sorted by name.
+func Headers(h http.Header) []NameValue {
+	headers := []NameValue{}
+	for name, values := range h {
+		for _, value := range values {
+			headers = append(headers, NameValue{name, value})
+		}
+	}
+	sort.SliceStable(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	return headers
+}
+
+// Header returns the headers as an http.Header.
+func Header(headers []NameValue) http.Header {
+	h := make(http.Header, len(headers))
+	for _, nv := range headers {
+		h.Add(nv.Name, nv.Value)
+	}
+	return h
+}
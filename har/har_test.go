@@ -0,0 +1,60 @@
+package har
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestContent(t *testing.T) {
+	testCases := [...]struct {
+		name     string
+		body     []byte
+		encoding string
+	}{
+		{"text", []byte(`{"a":"é"}`), ""},
+		{"binary", []byte{0xff, 0x00, 0xfe}, "base64"},
+		{"empty", []byte{}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewContent("application/octet-stream", tc.body)
+			if want, have := tc.encoding, c.Encoding; want != have {
+				t.Errorf("expected encoding %q, found %q", want, have)
+			}
+			if want, have := len(tc.body), c.Size; want != have {
+				t.Errorf("expected size %d, found %d", want, have)
+			}
+			body, err := c.Body()
+			if err != nil {
+				t.Fatalf("decoding the body: %v", err)
+			}
+			if want, have := string(tc.body), string(body); want != have {
+				t.Errorf("expected body %q, found %q", want, have)
+			}
+		})
+	}
+
+	t.Run("invalid base64", func(t *testing.T) {
+		if _, err := (Content{Text: "!", Encoding: "base64"}).Body(); err == nil {
+			t.Error("expected an error, found none")
+		}
+	})
+}
+
+func TestHeaders(t *testing.T) {
+	h := http.Header{
+		"X-B": {"1", "2"},
+		"X-A": {"3"},
+	}
+
+	headers := Headers(h)
+	if want := []NameValue{{"X-A", "3"}, {"X-B", "1"}, {"X-B", "2"}}; !reflect.DeepEqual(want, headers) {
+		t.Errorf("expected %v, found %v", want, headers)
+	}
+
+	if have := Header(headers); !reflect.DeepEqual(h, have) {
+		t.Errorf("expected %v, found %v", h, have)
+	}
+}
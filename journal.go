@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// journalPath is the path of the journal query endpoint.
+const journalPath = adminPrefix + "requests"
+
+// maxJournalBody is the size of the request bodies beyond which they are
+// truncated in the journal.
+const maxJournalBody = 64 << 10
+
+type matcher interface {
+	Match(string, string) (string, bool)
+}
+
+// journalEntry is a request recorded in the journal.
+type journalEntry struct {
+	ID     uint64      `json:"id"`
+	Time   time.Time   `json:"time"`
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+
+	// Body is the request body, or its base64 encoding if BodyEncoding is
+	// "base64". Only its first maxJournalBody bytes are recorded.
+	Body          string `json:"body"`
+	BodyEncoding  string `json:"bodyEncoding,omitempty"`
+	BodyTruncated bool   `json:"bodyTruncated,omitempty"`
+
+	// Matched is the key of the saved response that matched the request,
+	// if any.
+	Matched string `json:"matched,omitempty"`
+
+	Status int `json:"status"`
+}
+
+// Journal is a middleware handler that records the last requests in a ring
+// buffer, and serves them at journalPath, or as an HTTP Archive at
+// journalPath followed by ".har". The requests to the administrative API are
+// not recorded.
+type Journal struct {
+	next      http.Handler
+	resources matcher
+	size      int
+
+	mu      sync.Mutex
+	entries []journalEntry
+	head    int
+	total   uint64
+}
+
+// newJournal returns a new Journal instance, recording up to size requests.
+func newJournal(next http.Handler, resources matcher, size int) *Journal {
+	return &Journal{
+		next:      next,
+		resources: resources,
+		size:      size,
+	}
+}
+
+func (j *Journal) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	switch req.URL.Path {
+	case journalPath:
+		j.serveJournal(rw, req)
+		return
+	case journalPath + ".har":
+		if allowMethods(rw, req, http.MethodGet) {
+			writeJSON(rw, journalToHAR(j.find(func(journalEntry) bool { return true }), baseURL(req)))
+		}
+		return
+	}
+
+	if strings.HasPrefix(req.URL.Path, adminPrefix) || j.size <= 0 {
+		j.next.ServeHTTP(rw, req)
+		return
+	}
+
+	// Only the beginning of the body is read ahead; the rest is streamed to
+	// the next handler
+	head, err := ioutil.ReadAll(io.LimitReader(req.Body, maxJournalBody+1))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), req.Body), req.Body}
+
+	e := journalEntry{
+		Time:   time.Now(),
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header.Clone(),
+	}
+	if len(head) > maxJournalBody {
+		head, e.BodyTruncated = head[:maxJournalBody], true
+	}
+	if utf8.Valid(head) {
+		e.Body = string(head)
+	} else {
+		e.Body, e.BodyEncoding = base64.StdEncoding.EncodeToString(head), "base64"
+	}
+
+	method := req.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	e.Matched, _ = j.resources.Match(method, req.URL.String())
+
+	rr := &responseWriterRecorder{ResponseWriter: rw}
+	j.next.ServeHTTP(rr, req)
+
+	e.Status = rr.status
+	if e.Status == 0 {
+		e.Status = http.StatusOK
+	}
+
+	j.record(e)
+}
+
+// record adds the entry to the journal, overwriting the oldest one if the
+// journal is full.
+func (j *Journal) record(e journalEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.total++
+	e.ID = j.total
+
+	if len(j.entries) < j.size {
+		j.entries = append(j.entries, e)
+		return
+	}
+
+	j.entries[j.head] = e
+	j.head = (j.head + 1) % j.size
+}
+
+// find returns the recorded entries satisfying keep, oldest first.
+func (j *Journal) find(keep func(journalEntry) bool) []journalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	found := []journalEntry{}
+	for _, e := range append(j.entries[j.head:len(j.entries):len(j.entries)], j.entries[:j.head]...) {
+		if keep(e) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func (j *Journal) clear() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries, j.head = nil, 0
+}
+
+// serveJournal lists the recorded requests, optionally filtered by the
+// "method" and "path" query parameters, or clears the journal.
+func (j *Journal) serveJournal(rw http.ResponseWriter, req *http.Request) {
+	if !allowMethods(rw, req, http.MethodGet, http.MethodDelete) {
+		return
+	}
+
+	if req.Method == http.MethodDelete {
+		j.clear()
+		rw.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	method := strings.ToUpper(req.URL.Query().Get("method"))
+	path := req.URL.Query().Get("path")
+
+	writeJSON(rw, j.find(func(e journalEntry) bool {
+		if method != "" && e.Method != method {
+			return false
+		}
+		if path != "" && strings.SplitN(e.URL, "?", 2)[0] != path {
+			return false
+		}
+		return true
+	}))
+}
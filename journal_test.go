@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pierreprinetti/apimock/har"
+)
+
+type testmatcher map[string]string
+
+func (tm testmatcher) Match(method, path string) (string, bool) {
+	k, ok := tm[method+" "+path]
+	return k, ok
+}
+
+func TestJournal(t *testing.T) {
+	// next echoes the request body with the status 201
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		rw.WriteHeader(http.StatusCreated)
+		rw.Write(body)
+	})
+
+	newTestJournal := func(size int) *Journal {
+		return newJournal(next, testmatcher{"GET /items/1": "/items/%7Bid%7D"}, size)
+	}
+
+	do := func(j *Journal, method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("X-Test", "yes")
+		j.ServeHTTP(rec, req)
+		return rec
+	}
+
+	query := func(t *testing.T, j *Journal, target string) []journalEntry {
+		rec := do(j, "GET", target, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, found %d", rec.Code)
+		}
+		var entries []journalEntry
+		if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+			t.Fatalf("decoding the journal: %v", err)
+		}
+		return entries
+	}
+
+	t.Run("records the requests", func(t *testing.T) {
+		j := newTestJournal(10)
+
+		if rec := do(j, "POST", "/orders?x=1", `{"id":1}`); rec.Body.String() != `{"id":1}` {
+			t.Errorf("expected the body to be passed on, found %q", rec.Body.String())
+		}
+		do(j, "HEAD", "/items/1", "")
+
+		entries := query(t, j, journalPath)
+		if want, have := 2, len(entries); want != have {
+			t.Fatalf("expected %d entries, found %d", want, have)
+		}
+
+		e := entries[0]
+		if e.ID != 1 || e.Method != "POST" || e.URL != "/orders?x=1" || e.Body != `{"id":1}` ||
+			e.Header.Get("X-Test") != "yes" || e.Status != 201 || e.Time.IsZero() || e.Matched != "" {
+			t.Errorf("unexpected entry %+v", e)
+		}
+		if want, have := "/items/%7Bid%7D", entries[1].Matched; want != have {
+			t.Errorf("expected matched %q, found %q", want, have)
+		}
+	})
+
+	t.Run("filters the requests", func(t *testing.T) {
+		j := newTestJournal(10)
+		do(j, "POST", "/orders", "a")
+		do(j, "POST", "/orders?page=2", "b")
+		do(j, "GET", "/orders", "")
+		do(j, "POST", "/items", "")
+
+		entries := query(t, j, journalPath+"?method=post&path=/orders")
+		if want, have := 2, len(entries); want != have {
+			t.Fatalf("expected %d entries, found %d", want, have)
+		}
+		if entries[0].Body != "a" || entries[1].Body != "b" {
+			t.Errorf("unexpected entries %+v", entries)
+		}
+
+		if entries := query(t, j, journalPath+"?path=/unknown"); len(entries) != 0 {
+			t.Errorf("expected no entries, found %+v", entries)
+		}
+	})
+
+	t.Run("keeps the last requests", func(t *testing.T) {
+		j := newTestJournal(2)
+		for _, body := range []string{"a", "b", "c"} {
+			do(j, "POST", "/orders", body)
+		}
+
+		entries := query(t, j, journalPath)
+		if len(entries) != 2 || entries[0].Body != "b" || entries[1].Body != "c" || entries[1].ID != 3 {
+			t.Errorf("unexpected entries %+v", entries)
+		}
+	})
+
+	t.Run("truncates the large bodies", func(t *testing.T) {
+		j := newTestJournal(10)
+		body := strings.Repeat("a", maxJournalBody+10)
+		if rec := do(j, "POST", "/orders", body); rec.Body.String() != body {
+			t.Errorf("expected the whole body to be passed on, found %d bytes", rec.Body.Len())
+		}
+
+		e := query(t, j, journalPath)[0]
+		if want, have := maxJournalBody, len(e.Body); want != have || !e.BodyTruncated {
+			t.Errorf("expected a truncated body of %d bytes, found %d bytes, truncated %v", want, have, e.BodyTruncated)
+		}
+	})
+
+	t.Run("encodes the binary bodies", func(t *testing.T) {
+		j := newTestJournal(10)
+		do(j, "POST", "/orders", "\xff\xfe\x00abc")
+
+		e := query(t, j, journalPath)[0]
+		if e.Body != "//4AYWJj" || e.BodyEncoding != "base64" || e.BodyTruncated {
+			t.Errorf("unexpected entry %+v", e)
+		}
+	})
+
+	t.Run("exports the requests as HAR", func(t *testing.T) {
+		j := newTestJournal(10)
+		do(j, "POST", "/orders", "a")
+
+		rec := do(j, "GET", journalPath+".har", "")
+		var archive har.HAR
+		if err := json.Unmarshal(rec.Body.Bytes(), &archive); err != nil {
+			t.Fatalf("decoding the archive: %v", err)
+		}
+		if len(archive.Log.Entries) != 1 || archive.Log.Entries[0].Request.URL != "http://example.com/orders" {
+			t.Errorf("unexpected archive %+v", archive)
+		}
+	})
+
+	t.Run("skips the administrative API", func(t *testing.T) {
+		j := newTestJournal(10)
+		do(j, "GET", adminPrefix+"keys", "")
+
+		if entries := query(t, j, journalPath); len(entries) != 0 {
+			t.Errorf("expected no entries, found %+v", entries)
+		}
+	})
+
+	t.Run("clears the journal", func(t *testing.T) {
+		j := newTestJournal(10)
+		do(j, "POST", "/orders", "a")
+
+		if rec := do(j, "DELETE", journalPath, ""); rec.Code != http.StatusNoContent {
+			t.Errorf("expected status 204, found %d", rec.Code)
+		}
+		if entries := query(t, j, journalPath); len(entries) != 0 {
+			t.Errorf("expected no entries, found %+v", entries)
+		}
+	})
+
+	t.Run("can be disabled", func(t *testing.T) {
+		j := newTestJournal(0)
+		do(j, "POST", "/orders", "a")
+
+		if entries := query(t, j, journalPath); len(entries) != 0 {
+			t.Errorf("expected no entries, found %+v", entries)
+		}
+	})
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"time"
@@ -40,6 +43,24 @@ func (rr *responseWriterRecorder) Write(b []byte) (int, error) {
 	return rr.ResponseWriter.Write(b)
 }
 
+// Flush sends the buffered data to the client, if the underlying
+// ResponseWriter supports it.
+func (rr *responseWriterRecorder) Flush() {
+	if flusher, ok := rr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the next handlers take over the connection, if the underlying
+// ResponseWriter supports it.
+func (rr *responseWriterRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the ResponseWriter does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
+
 func (l *Logger) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	start := time.Now()
 
@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -64,3 +65,23 @@ func TestResponseWriterRecorderWrite(t *testing.T) {
 		}
 	})
 }
+
+func TestResponseWriterRecorderHijack(t *testing.T) {
+	t.Run("fails if the underlying rw can't hijack", func(t *testing.T) {
+		rr := &responseWriterRecorder{ResponseWriter: &testrw{}}
+		if _, _, err := rr.Hijack(); err == nil {
+			t.Error("expected an error, found none")
+		}
+	})
+}
+
+func TestResponseWriterRecorderFlush(t *testing.T) {
+	t.Run("calls the underlying rw.Flush", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rr := &responseWriterRecorder{ResponseWriter: rec}
+		rr.Flush()
+		if !rec.Flushed {
+			t.Error("rw.Flush has not been called")
+		}
+	})
+}
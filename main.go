@@ -4,21 +4,39 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/pierreprinetti/apimock/openapi"
 	"github.com/pierreprinetti/apimock/store"
 )
 
-func newRouter(resources router) http.Handler {
-	get := getHandler(resources)
+// newRouter returns the handler of the mocked API. The GET and HEAD requests
+// with no saved response are passed to fallback, if not nil.
+func newRouter(resources router, fallback http.Handler) http.Handler {
+	get := getHandler(resources, fallback)
+	head := headHandler(resources, fallback)
 	put := putHandler(resources)
+	post := postHandler(resources)
+	patch := patchHandler(resources)
 	del := deleteHandler(resources)
 
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if savedHandler(resources, rw, req) {
+			return
+		}
+
 		switch req.Method {
 		case http.MethodGet:
 			get(rw, req)
+		case http.MethodHead:
+			head(rw, req)
 		case http.MethodPut:
 			put(rw, req)
+		case http.MethodPost:
+			post(rw, req)
+		case http.MethodPatch:
+			patch(rw, req)
 		case http.MethodDelete:
 			del(rw, req)
 		case http.MethodOptions:
@@ -32,16 +50,108 @@ func newRouter(resources router) http.Handler {
 }
 
 func main() {
+	queryMatching, err := store.ParseQueryMatching(getenv("QUERY_MATCHING", "exact"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	latency, err := store.ParseLatency(getenv("LATENCY", ""))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bandwidth, err := store.ParseBandwidth(getenv("BANDWIDTH", ""))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	chaos, err := parseChaos(getenv("CHAOS", ""))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	journalSize, err := strconv.Atoi(getenv("JOURNAL_SIZE", "1000"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	resources := store.New(
 		store.WithDefaultContentType(getenv("DEFAULT_CONTENT_TYPE", "text/plain")),
 		store.WithContentTypeOverride(getenv("FORCED_CONTENT_TYPE", "")),
+		store.WithPersistence(getenv("PERSISTENCE_FILE", "")),
+		store.WithQueryMatching(queryMatching),
+		store.WithLatency(latency),
+		store.WithBandwidth(bandwidth),
 	)
 
-	apimock := newRouter(resources)
+	if err := resources.Restore(); err != nil {
+		log.Fatal(err)
+	}
+
+	var spec *openapi.Document
+	if file := getenv("OPENAPI_FILE", ""); file != "" {
+		if spec, err = loadSpec(resources, file); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	withCorsHeaders := newCors(apimock)
+	if file := getenv("HAR_FILE", ""); file != "" {
+		if err := loadHAR(resources, file); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var fallback http.Handler
+	if upstream := getenv("RECORD_UPSTREAM", ""); upstream != "" {
+		u, err := parseUpstream(upstream)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fallback = newRecorder(u, resources)
+	}
+
+	var apimock http.Handler = newRouter(resources, fallback)
+	if upstream := getenv("PROXY_UPSTREAM", ""); upstream != "" {
+		if fallback != nil {
+			log.Fatal("RECORD_UPSTREAM and PROXY_UPSTREAM are mutually exclusive")
+		}
+		u, err := parseUpstream(upstream)
+		if err != nil {
+			log.Fatal(err)
+		}
+		apimock = newPassthrough(apimock, resources, u)
+	}
+
+	if spec != nil {
+		apimock = newValidator(apimock, spec)
+	}
+
+	withChaos := newChaos(apimock, resources, chaos)
+	withAdmin := newAdmin(withChaos, resources)
+	withJournal := newJournal(withAdmin, resources, journalSize)
+	withCorsHeaders := newCors(withJournal)
 	withLogging := newLogger(withCorsHeaders)
 
+	if dir := getenv("FIXTURES_DIR", ""); dir != "" {
+		interval, err := time.ParseDuration(getenv("FIXTURES_WATCH", "1s"))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		watcher, err := newFixturesWatcher(resources, dir, withLogging.Logger)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := loadFixtures(resources, dir); err != nil {
+			log.Fatal(err)
+		}
+
+		if interval > 0 {
+			go watcher.watch(interval, nil)
+		}
+	}
+
 	if err := http.ListenAndServe(
 		getenv("HOST", ":"+getenv("PORT", "8800")), withLogging); err != nil {
 		log.Fatal(err)
@@ -125,7 +125,7 @@ func TestMain(t *testing.T) {
 		}
 	})
 
-	t.Run("POST call not implemented", func(t *testing.T) {
+	t.Run("POST call creates an entry", func(t *testing.T) {
 
 		// Run the application
 		srvAddr := "localhost:29110"
@@ -142,14 +142,161 @@ func TestMain(t *testing.T) {
 		// Define the data that will be sent and the expected
 		targetEndpoint := "http://" + srvAddr + "/endpoint3"
 
-		// Perform the OPTIONS call
+		// Perform the POST call twice
 		var client http.Client
-		req, _ := http.NewRequest("POST", targetEndpoint, nil)
-		res, err := client.Do(req)
+		for _, expectedLocation := range []string{"/endpoint3/1", "/endpoint3/2"} {
+			req, _ := http.NewRequest("POST", targetEndpoint, strings.NewReader("yay"))
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("calling POST: %v", err)
+			}
+
+			// Test the response code
+			if want, have := 201, res.StatusCode; want != have {
+				t.Errorf("expected response status code %d, found %d", want, have)
+			}
+
+			// Test the response location
+			if want, have := expectedLocation, res.Header.Get("Location"); want != have {
+				t.Errorf("expected response location %q, found %q", want, have)
+			}
+		}
+
+		// Perform the GET call on the created entry
+		res, err := http.Get("http://" + srvAddr + "/endpoint3/2")
+		if err != nil {
+			t.Fatalf("calling GET: %v", err)
+		}
+
+		// Test the GET response code
+		if want, have := 200, res.StatusCode; want != have {
+			t.Errorf("expected GET response status code %d, found %d", want, have)
+		}
+	})
+
+	t.Run("per-method responses", func(t *testing.T) {
+
+		// Run the application
+		srvAddr := "localhost:29112"
+		os.Setenv("HOST", srvAddr)
+		defer os.Unsetenv("HOST")
+
+		go func() {
+			main()
+		}()
+
+		// Make sure that the http listener is in place
+		time.Sleep(time.Millisecond)
+
+		// Define the data that will be sent and the expected
+		targetEndpoint := "http://" + srvAddr + "/login"
+
+		// Configure the POST response
+		var client http.Client
+		req, _ := http.NewRequest("PUT", targetEndpoint, strings.NewReader("token"))
+		req.Header.Set("X-Apimock-Method", "POST")
+		if _, err := client.Do(req); err != nil {
+			t.Fatalf("calling PUT: %v", err)
+		}
+
+		// Perform the POST call
+		res, err := client.Post(targetEndpoint, "text/plain", strings.NewReader("credentials"))
 		if err != nil {
 			t.Fatalf("calling POST: %v", err)
 		}
 
+		// Test the POST response
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatalf("reading the POST response body: %v", err)
+		}
+
+		if want, have := "token", string(body); want != have {
+			t.Errorf("expected POST response body %q, found %q", want, have)
+		}
+
+		// Test that no GET response was configured
+		res, err = http.Get(targetEndpoint)
+		if err != nil {
+			t.Fatalf("calling GET: %v", err)
+		}
+
+		if want, have := 404, res.StatusCode; want != have {
+			t.Errorf("expected GET response status code %d, found %d", want, have)
+		}
+	})
+
+	t.Run("HEAD call", func(t *testing.T) {
+
+		// Run the application
+		srvAddr := "localhost:29113"
+		os.Setenv("HOST", srvAddr)
+		defer os.Unsetenv("HOST")
+
+		go func() {
+			main()
+		}()
+
+		// Make sure that the http listener is in place
+		time.Sleep(time.Millisecond)
+
+		// Define the data that will be sent and the expected
+		targetEndpoint := "http://" + srvAddr + "/endpoint5"
+		expectedBody := strings.Repeat("large body ", 1000)
+
+		// Perform the PUT call
+		var client http.Client
+		req, _ := http.NewRequest("PUT", targetEndpoint, strings.NewReader(expectedBody))
+		req.Header.Set("X-Apimock-Header-Cache-Control", "no-cache")
+		if _, err := client.Do(req); err != nil {
+			t.Fatalf("calling PUT: %v", err)
+		}
+
+		// Perform the HEAD call
+		res, err := http.Head(targetEndpoint)
+		if err != nil {
+			t.Fatalf("calling HEAD: %v", err)
+		}
+
+		// Test the response
+		if want, have := 200, res.StatusCode; want != have {
+			t.Errorf("expected response status code %d, found %d", want, have)
+		}
+
+		if want, have := int64(len(expectedBody)), res.ContentLength; want != have {
+			t.Errorf("expected response content length %d, found %d", want, have)
+		}
+
+		if want, have := "no-cache", res.Header.Get("Cache-Control"); want != have {
+			t.Errorf("expected response Cache-Control %q, found %q", want, have)
+		}
+	})
+
+	t.Run("TRACE call not implemented", func(t *testing.T) {
+
+		// Run the application
+		srvAddr := "localhost:29111"
+		os.Setenv("HOST", srvAddr)
+		defer os.Unsetenv("HOST")
+
+		go func() {
+			main()
+		}()
+
+		// Make sure that the http listener is in place
+		time.Sleep(time.Millisecond)
+
+		// Define the data that will be sent and the expected
+		targetEndpoint := "http://" + srvAddr + "/endpoint4"
+
+		// Perform the TRACE call
+		var client http.Client
+		req, _ := http.NewRequest("TRACE", targetEndpoint, nil)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("calling TRACE: %v", err)
+		}
+
 		// Test the response code
 		if want, have := 501, res.StatusCode; want != have {
 			t.Errorf("expected response status code %d, found %d", want, have)
@@ -0,0 +1,348 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Mock is the response that mocks an operation.
+type Mock struct {
+	Method string
+
+	// Path is the escaped path of the operation, prefixed with the base
+	// path. Its parameters are kept between braces, so that it can be saved
+	// as a path template.
+	Path string
+
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Mocks returns a mock for every operation of the document, sorted by path
+// and method.
+// The response of a mock is the first successful response of the operation,
+// or its default response. Its body is the example of the response, the
+// first of its named examples, or data synthesised from its schema.
+func (d *Document) Mocks() ([]Mock, error) {
+	var mocks []Mock
+	for path, item := range d.Paths {
+		for method, op := range item.operations() {
+			m, err := d.mock(op)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			m.Method = method
+			m.Path = (&url.URL{Path: d.BasePath() + path}).EscapedPath()
+			mocks = append(mocks, m)
+		}
+	}
+
+	sort.Slice(mocks, func(i, j int) bool {
+		if mocks[i].Path != mocks[j].Path {
+			return mocks[i].Path < mocks[j].Path
+		}
+		return mocks[i].Method < mocks[j].Method
+	})
+
+	return mocks, nil
+}
+
+// mock builds the response of the operation.
+func (d *Document) mock(op *Operation) (Mock, error) {
+	code, status := successfulResponse(op.Responses)
+	if code == "" {
+		return Mock{Status: http.StatusOK}, nil
+	}
+
+	res, err := d.response(op.Responses[code])
+	if err != nil {
+		return Mock{}, err
+	}
+
+	contentType := preferredMediaType(res.Content)
+	if contentType == "" {
+		return Mock{Status: status}, nil
+	}
+	mt := res.Content[contentType]
+
+	example := mt.Example
+	if len(example) == 0 {
+		names := make([]string, 0, len(mt.Examples))
+		for name := range mt.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if value := mt.Examples[name].Value; len(value) > 0 {
+				example = value
+				break
+			}
+		}
+	}
+
+	if len(example) == 0 && mt.Schema != nil {
+		sample, err := d.sample(mt.Schema, 0)
+		if err != nil {
+			return Mock{}, err
+		}
+		if example, err = json.Marshal(sample); err != nil {
+			return Mock{}, err
+		}
+	}
+
+	return Mock{
+		Status:      status,
+		ContentType: contentType,
+		Body:        encodeBody(contentType, example),
+	}, nil
+}
+
+// successfulResponse returns the code of the lowest successful response, or
+// of the default response, along with the corresponding status code.
+func successfulResponse(responses map[string]Response) (string, int) {
+	var codes []string
+	for code := range responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			if status, err := strconv.Atoi(code); err == nil {
+				return code, status
+			}
+			// Ranges such as "2XX"
+			return code, http.StatusOK
+		}
+	}
+
+	if _, ok := responses["default"]; ok {
+		return "default", http.StatusOK
+	}
+
+	return "", 0
+}
+
+// preferredMediaType returns "application/json" if it is available, then
+// the other JSON media types, then the first one in alphabetical order.
+func preferredMediaType(content map[string]MediaType) string {
+	if _, ok := content["application/json"]; ok {
+		return "application/json"
+	}
+
+	var types []string
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	for _, t := range types {
+		if isJSON(t) {
+			return t
+		}
+	}
+
+	if len(types) > 0 {
+		return types[0]
+	}
+	return ""
+}
+
+func isJSON(mediaType string) bool {
+	mediaType = strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0])
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// encodeBody returns the body of the given media type holding the JSON
+// value. JSON strings are sent unquoted in non-JSON bodies.
+func encodeBody(mediaType string, value json.RawMessage) []byte {
+	if !isJSON(mediaType) {
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			return []byte(s)
+		}
+	}
+	return value
+}
+
+// maxSampleDepth bounds the nesting of the synthesised data, so that
+// recursive schemas terminate.
+const maxSampleDepth = 8
+
+// sample synthesises a value conforming to the schema. It prefers the
+// example, default and enum values of the schema to made-up ones.
+func (d *Document) sample(s *Schema, depth int) (interface{}, error) {
+	s, err := d.schema(s)
+	if err != nil || s == nil {
+		return nil, err
+	}
+
+	if depth > maxSampleDepth {
+		if s.Type == "array" {
+			return []interface{}{}, nil
+		}
+		return nil, nil
+	}
+
+	switch {
+	case len(s.Example) > 0:
+		return s.Example, nil
+	case len(s.Default) > 0:
+		return s.Default, nil
+	case len(s.Enum) > 0:
+		return s.Enum[0], nil
+	case len(s.AllOf) > 0:
+		return d.sampleAllOf(s, depth)
+	case len(s.OneOf) > 0:
+		return d.sample(s.OneOf[0], depth+1)
+	case len(s.AnyOf) > 0:
+		return d.sample(s.AnyOf[0], depth+1)
+	}
+
+	switch s.Type {
+	case "object":
+		return d.sampleProperties(s, depth)
+	case "array":
+		item, err := d.sample(s.Items, depth+1)
+		if err != nil || item == nil {
+			return []interface{}{}, err
+		}
+		n := 1
+		if s.MinItems != nil && *s.MinItems > n {
+			n = *s.MinItems
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			items[i] = item
+		}
+		return items, nil
+	case "string":
+		return sampleString(s), nil
+	case "integer":
+		return int64(sampleNumber(s, true)), nil
+	case "number":
+		return sampleNumber(s, false), nil
+	case "boolean":
+		return true, nil
+	case "":
+		if len(s.Properties) > 0 {
+			return d.sampleProperties(s, depth)
+		}
+	}
+
+	return nil, nil
+}
+
+// sampleAllOf merges the samples of the subschemas, if they are objects.
+func (d *Document) sampleAllOf(s *Schema, depth int) (interface{}, error) {
+	merged, err := d.sampleProperties(s, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sub := range s.AllOf {
+		v, err := d.sample(sub, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			if v != nil && len(merged) == 0 {
+				return v, nil
+			}
+			continue
+		}
+		for name, value := range object {
+			merged[name] = value
+		}
+	}
+
+	return merged, nil
+}
+
+// sampleProperties samples the properties of s, except the write-only ones
+// that the responses don't send.
+func (d *Document) sampleProperties(s *Schema, depth int) (map[string]interface{}, error) {
+	object := make(map[string]interface{}, len(s.Properties))
+	for name, property := range s.Properties {
+		resolved, err := d.schema(property)
+		if err != nil {
+			return nil, err
+		}
+		if resolved != nil && resolved.WriteOnly {
+			continue
+		}
+
+		v, err := d.sample(property, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		object[name] = v
+	}
+	return object, nil
+}
+
+func sampleString(s *Schema) string {
+	var v string
+	switch s.Format {
+	case "date":
+		v = "2020-01-01"
+	case "date-time":
+		v = "2020-01-01T00:00:00Z"
+	case "email":
+		v = "user@example.com"
+	case "uuid":
+		v = "00000000-0000-0000-0000-000000000000"
+	case "uri", "url":
+		v = "https://example.com"
+	case "hostname":
+		v = "example.com"
+	case "ipv4":
+		v = "192.0.2.1"
+	case "ipv6":
+		v = "2001:db8::1"
+	case "byte":
+		v = "c3RyaW5n"
+	default:
+		v = "string"
+	}
+
+	if s.MinLength != nil && len(v) < *s.MinLength {
+		v += strings.Repeat("x", *s.MinLength-len(v))
+	}
+	if s.MaxLength != nil && len(v) > *s.MaxLength {
+		v = v[:*s.MaxLength]
+	}
+	return v
+}
+
+func sampleNumber(s *Schema, integer bool) float64 {
+	var v float64
+	if s.Minimum != nil {
+		v = *s.Minimum
+		if s.ExclusiveMinimum {
+			if integer {
+				v = math.Floor(v) + 1
+			} else {
+				v++
+			}
+		}
+	}
+	if integer {
+		v = math.Ceil(v)
+	}
+	if s.Maximum != nil && v > *s.Maximum {
+		v = *s.Maximum
+		if integer {
+			v = math.Floor(v)
+		}
+	}
+	return v
+}
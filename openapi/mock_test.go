@@ -0,0 +1,118 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestMocks(t *testing.T) {
+	mocks, err := petstore(t).Mocks()
+	if err != nil {
+		t.Fatalf("building the mocks: %v", err)
+	}
+
+	want := []Mock{
+		{"GET", "/v1/health", 200, "text/plain", []byte("ok")},
+		{"GET", "/v1/pets", 200, "application/json", []byte(`[{"id":0,"name":"string","tag":"string"}]`)},
+		{"POST", "/v1/pets", 201, "application/json", []byte(`{"id": 1, "name": "Fido"}`)},
+		{"DELETE", "/v1/pets/%7BpetId%7D", 204, "", nil},
+		{"GET", "/v1/pets/%7BpetId%7D", 200, "application/json", []byte(`{"id": 42, "name": "Lassie", "tag": "dog"}`)},
+	}
+
+	if len(mocks) != len(want) {
+		t.Fatalf("expected %d mocks, found %d: %+v", len(want), len(mocks), mocks)
+	}
+
+	for i, m := range mocks {
+		w := want[i]
+		if m.Method != w.Method || m.Path != w.Path || m.Status != w.Status || m.ContentType != w.ContentType || string(m.Body) != string(w.Body) {
+			t.Errorf("expected mock %s %s %d %q %s, found %s %s %d %q %s",
+				w.Method, w.Path, w.Status, w.ContentType, w.Body,
+				m.Method, m.Path, m.Status, m.ContentType, m.Body)
+		}
+	}
+}
+
+func TestSample(t *testing.T) {
+	d := &Document{Components: Components{Schemas: map[string]*Schema{
+		"Tree": {Type: "object", Properties: map[string]*Schema{
+			"children": {Type: "array", Items: &Schema{Ref: "#/components/schemas/Tree"}},
+		}},
+	}}}
+
+	testCases := [...]struct {
+		schema string
+		want   string
+	}{
+		{`{"type": "string"}`, `"string"`},
+		{`{"type": "string", "format": "date-time"}`, `"2020-01-01T00:00:00Z"`},
+		{`{"type": "string", "format": "email"}`, `"user@example.com"`},
+		{`{"type": "string", "minLength": 10}`, `"stringxxxx"`},
+		{`{"type": "string", "maxLength": 3}`, `"str"`},
+		{`{"type": "integer", "minimum": 5}`, `5`},
+		{`{"type": "integer", "minimum": 5, "exclusiveMinimum": true}`, `6`},
+		{`{"type": "integer", "maximum": -3}`, `-3`},
+		{`{"type": "number", "minimum": 1.5}`, `1.5`},
+		{`{"type": "boolean"}`, `true`},
+		{`{"type": "string", "example": "hello", "default": "world"}`, `"hello"`},
+		{`{"type": "string", "default": "world"}`, `"world"`},
+		{`{"type": "string", "enum": ["a", "b"]}`, `"a"`},
+		{`{"type": "array", "items": {"type": "integer"}, "minItems": 2}`, `[0,0]`},
+		{`{"properties": {"a": {"type": "boolean"}}}`, `{"a":true}`},
+		{`{"properties": {"a": {"type": "boolean"}, "password": {"type": "string", "writeOnly": true}}}`, `{"a":true}`},
+		{`{"oneOf": [{"type": "integer"}, {"type": "string"}]}`, `0`},
+		{`{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `"string"`},
+		{`{"allOf": [{"properties": {"a": {"type": "integer"}}}, {"properties": {"b": {"type": "integer"}}}]}`, `{"a":0,"b":0}`},
+		{`{"$ref": "#/components/schemas/Tree"}`, `{"children":[{"children":[{"children":[{"children":[{"children":[]}]}]}]}]}`},
+		{`{}`, `null`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.schema, func(t *testing.T) {
+			v, err := d.sample(mustSchema(t, tc.schema), 0)
+			if err != nil {
+				t.Fatalf("sampling: %v", err)
+			}
+			have, _ := json.Marshal(v)
+			if string(have) != tc.want {
+				t.Errorf("expected %s, found %s", tc.want, have)
+			}
+		})
+	}
+
+	t.Run("unknown references", func(t *testing.T) {
+		if _, err := d.sample(&Schema{Ref: "#/components/schemas/Missing"}, 0); err == nil {
+			t.Error("expected an error, found none")
+		}
+	})
+}
+
+func ExampleDocument_Mocks() {
+	d, _ := Parse([]byte(`{
+		"openapi": "3.0.3",
+		"paths": {"/users/{id}": {"get": {"responses": {"200": {
+			"content": {"application/json": {"schema": {
+				"type": "object",
+				"properties": {"email": {"type": "string", "format": "email"}}
+			}}}
+		}}}}}
+	}`))
+
+	mocks, _ := d.Mocks()
+	for _, m := range mocks {
+		fmt.Println(m.Method, m.Path, m.Status, string(m.Body))
+	}
+	// Output: GET /users/%7Bid%7D 200 {"email":"user@example.com"}
+}
+
+// mustSchema decodes a JSON schema.
+func mustSchema(t *testing.T, data string) *Schema {
+	t.Helper()
+
+	var s Schema
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		t.Fatalf("decoding the schema: %v", err)
+	}
+	return &s
+}
@@ -0,0 +1,284 @@
+// Package openapi reads the subset of OpenAPI 3.0 documents that is needed
+// to mock an API and validate the requests to it: the operations, their
+// parameters, request bodies and responses, and the schemas describing them.
+//
+// Only the JSON serialisation of the documents is supported, as the standard
+// library has no YAML decoder. YAML documents can be converted beforehand,
+// for example with `yq -o=json`.
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidDocument is returned when a document can't be read.
+var ErrInvalidDocument = errors.New("invalid OpenAPI document")
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Servers    []Server            `json:"servers"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+
+	// patterns holds the compiled regular expressions of the schemas, by
+	// source.
+	patterns map[string]*regexp.Regexp
+}
+
+// Server is a server of the API.
+type Server struct {
+	URL string `json:"url"`
+}
+
+// PathItem holds the operations available on a path.
+type PathItem struct {
+	Get     *Operation `json:"get"`
+	Put     *Operation `json:"put"`
+	Post    *Operation `json:"post"`
+	Delete  *Operation `json:"delete"`
+	Options *Operation `json:"options"`
+	Head    *Operation `json:"head"`
+	Patch   *Operation `json:"patch"`
+	Trace   *Operation `json:"trace"`
+
+	// Parameters are shared by all the operations of the path.
+	Parameters []Parameter `json:"parameters"`
+}
+
+// operations returns the operations of the path item, by method.
+func (p PathItem) operations() map[string]*Operation {
+	ops := make(map[string]*Operation)
+	for method, op := range map[string]*Operation{
+		http.MethodGet:     p.Get,
+		http.MethodPut:     p.Put,
+		http.MethodPost:    p.Post,
+		http.MethodDelete:  p.Delete,
+		http.MethodOptions: p.Options,
+		http.MethodHead:    p.Head,
+		http.MethodPatch:   p.Patch,
+		http.MethodTrace:   p.Trace,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+// Operation is an API operation on a path.
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Parameters  []Parameter         `json:"parameters"`
+	RequestBody *RequestBody        `json:"requestBody"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter is a parameter of an operation.
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+
+	// Explode tells whether the array query parameters are repeated, which
+	// is the default, or their values are separated by commas.
+	Explode *bool `json:"explode"`
+}
+
+// RequestBody is the request body of an operation.
+type RequestBody struct {
+	Ref      string               `json:"$ref"`
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an operation.
+type Response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+// MediaType describes a body of a given media type.
+type MediaType struct {
+	Schema   *Schema            `json:"schema"`
+	Example  json.RawMessage    `json:"example"`
+	Examples map[string]Example `json:"examples"`
+}
+
+// Example is a named example.
+type Example struct {
+	Value json.RawMessage `json:"value"`
+}
+
+// Schema is a JSON Schema, as extended by OpenAPI 3.0.
+type Schema struct {
+	Ref string `json:"$ref"`
+
+	Type     string            `json:"type"`
+	Format   string            `json:"format"`
+	Nullable bool              `json:"nullable"`
+	Enum     []json.RawMessage `json:"enum"`
+	Example  json.RawMessage   `json:"example"`
+	Default  json.RawMessage   `json:"default"`
+
+	AllOf []*Schema `json:"allOf"`
+	OneOf []*Schema `json:"oneOf"`
+	AnyOf []*Schema `json:"anyOf"`
+
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+
+	Minimum          *float64 `json:"minimum"`
+	Maximum          *float64 `json:"maximum"`
+	ExclusiveMinimum bool     `json:"exclusiveMinimum"`
+	ExclusiveMaximum bool     `json:"exclusiveMaximum"`
+	MultipleOf       *float64 `json:"multipleOf"`
+	MinLength        *int     `json:"minLength"`
+	MaxLength        *int     `json:"maxLength"`
+	Pattern          string   `json:"pattern"`
+	MinItems         *int     `json:"minItems"`
+	MaxItems         *int     `json:"maxItems"`
+	UniqueItems      bool     `json:"uniqueItems"`
+
+	// ReadOnly properties are only sent in responses, and WriteOnly ones in
+	// requests: they are only required there.
+	ReadOnly  bool `json:"readOnly"`
+	WriteOnly bool `json:"writeOnly"`
+}
+
+// Components holds the reusable objects of the document.
+type Components struct {
+	Schemas       map[string]*Schema     `json:"schemas"`
+	Parameters    map[string]Parameter   `json:"parameters"`
+	RequestBodies map[string]RequestBody `json:"requestBodies"`
+	Responses     map[string]Response    `json:"responses"`
+}
+
+// Parse reads a JSON OpenAPI 3.0 document. OpenAPI 3.1 documents are
+// rejected.
+func Parse(data []byte) (*Document, error) {
+	// The version is checked first: the schemas of the other versions don't
+	// decode, and their errors would be misleading
+	var version struct {
+		OpenAPI string `json:"openapi"`
+	}
+	if err := json.Unmarshal(data, &version); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	if !strings.HasPrefix(version.OpenAPI, "3.0.") {
+		return nil, fmt.Errorf("%w: unsupported OpenAPI version %q, only 3.0 is supported", ErrInvalidDocument, version.OpenAPI)
+	}
+
+	var d Document
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+
+	if err := d.compilePatterns(); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// BasePath returns the path of the URL of the first server, without the
+// trailing slash. The paths of the operations are relative to it.
+func (d *Document) BasePath() string {
+	if len(d.Servers) == 0 {
+		return ""
+	}
+
+	u, err := url.Parse(d.Servers[0].URL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// refName returns the name of the component referenced by ref, if it is in
+// the given section of the components of the document.
+func refName(ref, section string) (string, error) {
+	prefix := "#/components/" + section + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("%w: unsupported reference %q", ErrInvalidDocument, ref)
+	}
+	return strings.TrimPrefix(ref, prefix), nil
+}
+
+// schema resolves the references of s.
+func (d *Document) schema(s *Schema) (*Schema, error) {
+	for depth := 0; s != nil && s.Ref != ""; depth++ {
+		if depth > 32 {
+			return nil, fmt.Errorf("%w: reference loop at %q", ErrInvalidDocument, s.Ref)
+		}
+		name, err := refName(s.Ref, "schemas")
+		if err != nil {
+			return nil, err
+		}
+		resolved, ok := d.Components.Schemas[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown schema %q", ErrInvalidDocument, s.Ref)
+		}
+		s = resolved
+	}
+	return s, nil
+}
+
+// parameter resolves the reference of p.
+func (d *Document) parameter(p Parameter) (Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, err := refName(p.Ref, "parameters")
+	if err != nil {
+		return Parameter{}, err
+	}
+	resolved, ok := d.Components.Parameters[name]
+	if !ok {
+		return Parameter{}, fmt.Errorf("%w: unknown parameter %q", ErrInvalidDocument, p.Ref)
+	}
+	return resolved, nil
+}
+
+// requestBody resolves the reference of b.
+func (d *Document) requestBody(b *RequestBody) (*RequestBody, error) {
+	if b == nil || b.Ref == "" {
+		return b, nil
+	}
+	name, err := refName(b.Ref, "requestBodies")
+	if err != nil {
+		return nil, err
+	}
+	resolved, ok := d.Components.RequestBodies[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown request body %q", ErrInvalidDocument, b.Ref)
+	}
+	return &resolved, nil
+}
+
+// response resolves the reference of r.
+func (d *Document) response(r Response) (Response, error) {
+	if r.Ref == "" {
+		return r, nil
+	}
+	name, err := refName(r.Ref, "responses")
+	if err != nil {
+		return Response{}, err
+	}
+	resolved, ok := d.Components.Responses[name]
+	if !ok {
+		return Response{}, fmt.Errorf("%w: unknown response %q", ErrInvalidDocument, r.Ref)
+	}
+	return resolved, nil
+}
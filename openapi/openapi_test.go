@@ -0,0 +1,129 @@
+package openapi
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// petstore parses the test document.
+func petstore(t *testing.T) *Document {
+	t.Helper()
+
+	data, err := ioutil.ReadFile("testdata/petstore.json")
+	if err != nil {
+		t.Fatalf("reading the document: %v", err)
+	}
+
+	d, err := Parse(data)
+	if err != nil {
+		t.Fatalf("parsing the document: %v", err)
+	}
+
+	return d
+}
+
+func TestParse(t *testing.T) {
+	testCases := [...]struct {
+		name string
+		data string
+		ok   bool
+	}{
+		{"3.0", `{"openapi": "3.0.3"}`, true},
+		{"3.1", `{"openapi": "3.1.0"}`, false},
+		{
+			"3.1 keywords",
+			`{"openapi": "3.1.0", "components": {"schemas": {"Age": {"type": ["integer", "null"], "exclusiveMinimum": 0}}}}`,
+			false,
+		},
+		{"swagger 2", `{"swagger": "2.0"}`, false},
+		{"yaml", "openapi: 3.0.3", false},
+		{
+			"supported pattern",
+			`{"openapi": "3.0.3", "components": {"schemas": {"Code": {"type": "string", "pattern": "^[0-9]{4}$"}}}}`,
+			true,
+		},
+		{
+			"unsupported pattern",
+			`{"openapi": "3.0.3", "components": {"schemas": {"Password": {"type": "string", "pattern": "^(?=.*[0-9]).{8,}$"}}}}`,
+			false,
+		},
+		{
+			"unsupported nested pattern",
+			`{"openapi": "3.0.3", "paths": {"/users": {"post": {"requestBody": {"content": {"application/json": {"schema": {
+				"type": "object", "additionalProperties": {"type": "array", "items": {"pattern": "(?<=a)b"}}
+			}}}}}}}}`,
+			false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.data))
+			if ok := err == nil; ok != tc.ok {
+				t.Fatalf("expected ok %v, found error %v", tc.ok, err)
+			}
+			if !tc.ok && !errors.Is(err, ErrInvalidDocument) {
+				t.Errorf("expected ErrInvalidDocument, found %v", err)
+			}
+		})
+	}
+
+	t.Run("explains that 3.1 is unsupported", func(t *testing.T) {
+		_, err := Parse([]byte(`{"openapi": "3.1.0", "components": {"schemas": {"Age": {"exclusiveMinimum": 0}}}}`))
+		if want := `unsupported OpenAPI version "3.1.0"`; err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error containing %q, found %v", want, err)
+		}
+	})
+}
+
+func TestBasePath(t *testing.T) {
+	testCases := [...]struct {
+		servers []Server
+		want    string
+	}{
+		{nil, ""},
+		{[]Server{{"https://example.com"}}, ""},
+		{[]Server{{"https://example.com/v1/"}, {"/v2"}}, "/v1"},
+		{[]Server{{"/api"}}, "/api"},
+	}
+
+	for _, tc := range testCases {
+		d := Document{Servers: tc.servers}
+		if have := d.BasePath(); have != tc.want {
+			t.Errorf("%v: expected %q, found %q", tc.servers, tc.want, have)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	d := &Document{Components: Components{Schemas: map[string]*Schema{
+		"A":    {Ref: "#/components/schemas/B"},
+		"B":    {Type: "string"},
+		"Loop": {Ref: "#/components/schemas/Loop"},
+	}}}
+
+	testCases := [...]struct {
+		ref  string
+		want string
+		ok   bool
+	}{
+		{"#/components/schemas/A", "string", true},
+		{"#/components/schemas/Missing", "", false},
+		{"#/components/schemas/Loop", "", false},
+		{"other.json#/Pet", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.ref, func(t *testing.T) {
+			s, err := d.schema(&Schema{Ref: tc.ref})
+			if ok := err == nil; ok != tc.ok {
+				t.Fatalf("expected ok %v, found error %v", tc.ok, err)
+			}
+			if tc.ok && s.Type != tc.want {
+				t.Errorf("expected type %q, found %q", tc.want, s.Type)
+			}
+		})
+	}
+}
@@ -0,0 +1,681 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationError describes why a request does not conform to the document.
+type ValidationError struct {
+	// In is the part of the request that is invalid, such as
+	// `query parameter "limit"` or "request body".
+	In string
+
+	// Pointer is the JSON pointer to the invalid value, within a JSON
+	// request body.
+	Pointer string
+
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Pointer != "" {
+		return fmt.Sprintf("%s at %q: %s", e.In, e.Pointer, e.Message)
+	}
+	return e.In + ": " + e.Message
+}
+
+// Validate checks the request against the operation declared for its method
+// and path, if any. The parameters and the request body are validated
+// against their schemas; the request body is read, and replaced with an
+// unread copy.
+// The returned error is a *ValidationError if the request is invalid; it is
+// nil if it is valid, or if the document declares no operation for it.
+func (d *Document) Validate(req *http.Request) error {
+	op, item, params, ok := d.route(req.Method, req.URL.Path)
+	if !ok {
+		return nil
+	}
+
+	parameters, err := d.parameters(item.Parameters, op.Parameters)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range parameters {
+		if err := d.validateParameter(req, p, params); err != nil {
+			return err
+		}
+	}
+
+	body, err := d.requestBody(op.RequestBody)
+	if err != nil || body == nil {
+		return err
+	}
+
+	return d.validateBody(req, body)
+}
+
+// route returns the operation answering the method and the path, along with
+// the values of the path parameters. Literal path segments are preferred to
+// parameters.
+func (d *Document) route(method, path string) (*Operation, PathItem, map[string]string, bool) {
+	base := d.BasePath()
+	if !strings.HasPrefix(path, base) {
+		return nil, PathItem{}, nil, false
+	}
+	segments := strings.Split(strings.TrimPrefix(path, base), "/")
+
+	templates := make([]string, 0, len(d.Paths))
+	for template := range d.Paths {
+		templates = append(templates, template)
+	}
+	sort.Strings(templates)
+
+	var (
+		found      bool
+		bestOp     *Operation
+		bestItem   PathItem
+		bestParams map[string]string
+	)
+	for _, template := range templates {
+		item := d.Paths[template]
+		op, ok := item.operations()[method]
+		if !ok {
+			continue
+		}
+
+		params, ok := matchPath(strings.Split(template, "/"), segments)
+		if !ok || (found && len(params) >= len(bestParams)) {
+			continue
+		}
+
+		found, bestOp, bestItem, bestParams = true, op, item, params
+	}
+
+	return bestOp, bestItem, bestParams, found
+}
+
+// matchPath matches the segments of a path against the segments of a path
+// template, and returns the values of the template parameters.
+func matchPath(template, segments []string) (map[string]string, bool) {
+	if len(template) != len(segments) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, t := range template {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") && segments[i] != "" {
+			params[t[1:len(t)-1]] = segments[i]
+			continue
+		}
+		if t != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// parameters resolves the parameters of the path and of the operation; the
+// latter override the former.
+func (d *Document) parameters(pathParameters, opParameters []Parameter) ([]Parameter, error) {
+	var parameters []Parameter
+	index := make(map[string]int)
+	for _, p := range append(append([]Parameter(nil), pathParameters...), opParameters...) {
+		p, err := d.parameter(p)
+		if err != nil {
+			return nil, err
+		}
+
+		k := p.In + " " + p.Name
+		if i, ok := index[k]; ok {
+			parameters[i] = p
+			continue
+		}
+		index[k] = len(parameters)
+		parameters = append(parameters, p)
+	}
+	return parameters, nil
+}
+
+func (d *Document) validateParameter(req *http.Request, p Parameter, pathParams map[string]string) error {
+	in := fmt.Sprintf("%s parameter %q", p.In, p.Name)
+
+	var values []string
+	switch p.In {
+	case "path":
+		if v, ok := pathParams[p.Name]; ok {
+			values = []string{v}
+		}
+	case "query":
+		values = req.URL.Query()[p.Name]
+	case "header":
+		values = req.Header.Values(p.Name)
+	case "cookie":
+		if c, err := req.Cookie(p.Name); err == nil {
+			values = []string{c.Value}
+		}
+	}
+
+	if len(values) == 0 {
+		if p.Required || p.In == "path" {
+			return &ValidationError{In: in, Message: "missing required parameter"}
+		}
+		return nil
+	}
+
+	s, err := d.schema(p.Schema)
+	if err != nil || s == nil {
+		return err
+	}
+
+	v, err := parseParameter(s, p, values)
+	if err != nil {
+		return &ValidationError{In: in, Message: err.Error()}
+	}
+
+	return d.validateValue(s, v, in, "")
+}
+
+// parseParameter converts the string values of a parameter to the type
+// declared by its schema.
+func parseParameter(s *Schema, p Parameter, values []string) (interface{}, error) {
+	if s.Type != "array" {
+		return parseScalar(s, values[0])
+	}
+
+	if p.Explode != nil && !*p.Explode || p.In != "query" {
+		values = strings.Split(values[0], ",")
+	}
+
+	items := s.Items
+	if items == nil {
+		items = &Schema{}
+	}
+
+	array := make([]interface{}, len(values))
+	for i, value := range values {
+		v, err := parseScalar(items, value)
+		if err != nil {
+			return nil, err
+		}
+		array[i] = v
+	}
+	return array, nil
+}
+
+func parseScalar(s *Schema, value string) (interface{}, error) {
+	switch s.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return json.Number(value), nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", value)
+		}
+		return b, nil
+	default:
+		return value, nil
+	}
+}
+
+func (d *Document) validateBody(req *http.Request, body *RequestBody) error {
+	const in = "request body"
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	if len(data) == 0 {
+		if body.Required {
+			return &ValidationError{In: in, Message: "missing required body"}
+		}
+		return nil
+	}
+
+	contentType := req.Header.Get("Content-Type")
+	mt, ok := mediaType(body.Content, contentType)
+	if !ok {
+		return &ValidationError{In: in, Message: fmt.Sprintf("unsupported content type %q", contentType)}
+	}
+
+	if !isJSON(contentType) || mt.Schema == nil {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return &ValidationError{In: in, Message: fmt.Sprintf("invalid JSON: %v", err)}
+	}
+
+	return d.validateValue(mt.Schema, v, in, "")
+}
+
+// mediaType returns the media type of the content matching the content
+// type, either exactly or through a wildcard such as "application/*".
+func mediaType(content map[string]MediaType, contentType string) (MediaType, bool) {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t = "application/octet-stream"
+	}
+
+	for _, candidate := range [...]string{t, strings.SplitN(t, "/", 2)[0] + "/*", "*/*"} {
+		if mt, ok := content[candidate]; ok {
+			return mt, true
+		}
+	}
+	return MediaType{}, false
+}
+
+// validateValue checks the value against the schema. The value is a JSON
+// value decoded with numbers as json.Number.
+func (d *Document) validateValue(s *Schema, v interface{}, in, pointer string) error {
+	s, err := d.schema(s)
+	if err != nil || s == nil {
+		return err
+	}
+
+	invalid := func(format string, a ...interface{}) error {
+		return &ValidationError{In: in, Pointer: pointer, Message: fmt.Sprintf(format, a...)}
+	}
+
+	if v == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return invalid("must not be null")
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		return invalid("must be one of the enumerated values")
+	}
+
+	for _, sub := range s.AllOf {
+		if err := d.validateValue(sub, v, in, pointer); err != nil {
+			return err
+		}
+	}
+
+	if len(s.AnyOf) > 0 {
+		var matched bool
+		for _, sub := range s.AnyOf {
+			if d.validateValue(sub, v, in, pointer) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return invalid("must match at least one schema of anyOf")
+		}
+	}
+
+	if len(s.OneOf) > 0 {
+		var matched int
+		for _, sub := range s.OneOf {
+			if d.validateValue(sub, v, in, pointer) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return invalid("must match exactly one schema of oneOf, matched %d", matched)
+		}
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if s.Type != "" && s.Type != "object" {
+			return invalid("must be of type %s", s.Type)
+		}
+		return d.validateObject(s, value, in, pointer)
+
+	case []interface{}:
+		if s.Type != "" && s.Type != "array" {
+			return invalid("must be of type %s", s.Type)
+		}
+		if s.MinItems != nil && len(value) < *s.MinItems {
+			return invalid("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(value) > *s.MaxItems {
+			return invalid("must have at most %d items", *s.MaxItems)
+		}
+		if s.UniqueItems && !uniqueItems(value) {
+			return invalid("must have unique items")
+		}
+		for i, item := range value {
+			if err := d.validateValue(s.Items, item, in, pointer+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+
+	case string:
+		if s.Type != "" && s.Type != "string" {
+			return invalid("must be of type %s", s.Type)
+		}
+		if n := utf8.RuneCountInString(value); s.MinLength != nil && n < *s.MinLength {
+			return invalid("must be at least %d characters long", *s.MinLength)
+		} else if s.MaxLength != nil && n > *s.MaxLength {
+			return invalid("must be at most %d characters long", *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			re, err := d.pattern(s.Pattern)
+			if err != nil {
+				return err
+			}
+			if !re.MatchString(value) {
+				return invalid("must match the pattern %q", s.Pattern)
+			}
+		}
+		if !validFormat(s.Format, value) {
+			return invalid("must be a valid %s", s.Format)
+		}
+
+	case json.Number:
+		if s.Type != "" && s.Type != "number" && s.Type != "integer" {
+			return invalid("must be of type %s", s.Type)
+		}
+		return validateNumber(s, value, invalid)
+
+	case bool:
+		if s.Type != "" && s.Type != "boolean" {
+			return invalid("must be of type %s", s.Type)
+		}
+	}
+
+	return nil
+}
+
+func (d *Document) validateObject(s *Schema, object map[string]interface{}, in, pointer string) error {
+	for _, name := range s.Required {
+		if _, ok := object[name]; ok {
+			continue
+		}
+		// The requests can't send the read-only properties
+		property, err := d.schema(s.Properties[name])
+		if err != nil {
+			return err
+		}
+		if property != nil && property.ReadOnly {
+			continue
+		}
+		return &ValidationError{In: in, Pointer: pointer, Message: fmt.Sprintf("missing required property %q", name)}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, ok := s.Properties[name]
+		if !ok {
+			var additional interface{}
+			if len(s.AdditionalProperties) > 0 {
+				if err := json.Unmarshal(s.AdditionalProperties, &additional); err != nil {
+					return fmt.Errorf("%w: invalid additionalProperties", ErrInvalidDocument)
+				}
+			}
+			switch additional := additional.(type) {
+			case bool:
+				if !additional {
+					return &ValidationError{In: in, Pointer: pointer, Message: fmt.Sprintf("unexpected property %q", name)}
+				}
+				continue
+			case map[string]interface{}:
+				property = &Schema{}
+				if err := json.Unmarshal(s.AdditionalProperties, property); err != nil {
+					return fmt.Errorf("%w: invalid additionalProperties", ErrInvalidDocument)
+				}
+			default:
+				continue
+			}
+		}
+
+		if err := d.validateValue(property, object[name], in, pointer+"/"+escapePointer(name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateNumber(s *Schema, value json.Number, invalid func(string, ...interface{}) error) error {
+	n, err := value.Float64()
+	if err != nil {
+		return invalid("must be a number")
+	}
+
+	if s.Type == "integer" {
+		if _, err := strconv.ParseInt(value.String(), 10, 64); err != nil {
+			return invalid("must be an integer")
+		}
+	}
+
+	if s.Minimum != nil {
+		if n < *s.Minimum || s.ExclusiveMinimum && n == *s.Minimum {
+			return invalid("must be greater than %s%v", orEqual(!s.ExclusiveMinimum), *s.Minimum)
+		}
+	}
+	if s.Maximum != nil {
+		if n > *s.Maximum || s.ExclusiveMaximum && n == *s.Maximum {
+			return invalid("must be less than %s%v", orEqual(!s.ExclusiveMaximum), *s.Maximum)
+		}
+	}
+	if s.MultipleOf != nil && *s.MultipleOf != 0 {
+		if q := n / *s.MultipleOf; q != float64(int64(q)) {
+			return invalid("must be a multiple of %v", *s.MultipleOf)
+		}
+	}
+
+	return nil
+}
+
+func orEqual(inclusive bool) string {
+	if inclusive {
+		return "or equal to "
+	}
+	return ""
+}
+
+// compilePatterns compiles the patterns of all the schemas of the document,
+// so that unsupported regular expressions are reported when the document is
+// loaded rather than when validating a request.
+func (d *Document) compilePatterns() error {
+	d.patterns = make(map[string]*regexp.Regexp)
+
+	for _, s := range d.Components.Schemas {
+		if err := d.compileSchema(s); err != nil {
+			return err
+		}
+	}
+	for _, p := range d.Components.Parameters {
+		if err := d.compileSchema(p.Schema); err != nil {
+			return err
+		}
+	}
+	for _, b := range d.Components.RequestBodies {
+		if err := d.compileContent(b.Content); err != nil {
+			return err
+		}
+	}
+	for _, r := range d.Components.Responses {
+		if err := d.compileContent(r.Content); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range d.Paths {
+		for _, p := range item.Parameters {
+			if err := d.compileSchema(p.Schema); err != nil {
+				return err
+			}
+		}
+		for _, op := range item.operations() {
+			for _, p := range op.Parameters {
+				if err := d.compileSchema(p.Schema); err != nil {
+					return err
+				}
+			}
+			if op.RequestBody != nil {
+				if err := d.compileContent(op.RequestBody.Content); err != nil {
+					return err
+				}
+			}
+			for _, r := range op.Responses {
+				if err := d.compileContent(r.Content); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *Document) compileContent(content map[string]MediaType) error {
+	for _, mt := range content {
+		if err := d.compileSchema(mt.Schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileSchema compiles the patterns of s and of its subschemas. The
+// references are not followed: the referenced schemas are compiled with the
+// components.
+func (d *Document) compileSchema(s *Schema) error {
+	if s == nil {
+		return nil
+	}
+
+	if s.Pattern != "" {
+		if _, ok := d.patterns[s.Pattern]; !ok {
+			re, err := regexp.Compile(s.Pattern)
+			if err != nil {
+				return fmt.Errorf("%w: unsupported pattern %q: %v", ErrInvalidDocument, s.Pattern, err)
+			}
+			d.patterns[s.Pattern] = re
+		}
+	}
+
+	subschemas := []*Schema{s.Items}
+	subschemas = append(subschemas, s.AllOf...)
+	subschemas = append(subschemas, s.OneOf...)
+	subschemas = append(subschemas, s.AnyOf...)
+	for _, property := range s.Properties {
+		subschemas = append(subschemas, property)
+	}
+	if len(s.AdditionalProperties) > 0 && s.AdditionalProperties[0] == '{' {
+		var additional Schema
+		if err := json.Unmarshal(s.AdditionalProperties, &additional); err != nil {
+			return fmt.Errorf("%w: invalid additionalProperties", ErrInvalidDocument)
+		}
+		subschemas = append(subschemas, &additional)
+	}
+
+	for _, sub := range subschemas {
+		if err := d.compileSchema(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pattern returns the compiled pattern. The patterns of the documents read
+// with Parse are compiled beforehand.
+func (d *Document) pattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := d.patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unsupported pattern %q: %v", ErrInvalidDocument, pattern, err)
+	}
+	return re, nil
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// validFormat checks the most common string formats. Unknown formats are
+// always valid.
+func validFormat(format, value string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "email":
+		i := strings.LastIndex(value, "@")
+		return i > 0 && i < len(value)-1
+	case "uuid":
+		return uuidPattern.MatchString(value)
+	case "uri":
+		u, err := url.Parse(value)
+		return err == nil && u.IsAbs()
+	default:
+		return true
+	}
+}
+
+// inEnum reports whether the value is equal to one of the enumerated JSON
+// values.
+func inEnum(enum []json.RawMessage, v interface{}) bool {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	for _, candidate := range enum {
+		if equalJSON(candidate, encoded) {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueItems(items []interface{}) bool {
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		encoded, _ := json.Marshal(item)
+		if seen[string(encoded)] {
+			return false
+		}
+		seen[string(encoded)] = true
+	}
+	return true
+}
+
+// equalJSON compares two JSON documents, ignoring the insignificant
+// whitespace.
+func equalJSON(a, b []byte) bool {
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return false
+	}
+	return ca.String() == cb.String()
+}
+
+// escapePointer escapes a JSON pointer reference token.
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
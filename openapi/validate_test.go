@@ -0,0 +1,131 @@
+package openapi
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	d := petstore(t)
+
+	testCases := [...]struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"valid list", "GET", "/v1/pets?limit=10", "", "", ""},
+		{"undeclared operation", "PUT", "/v1/pets", "", "{", ""},
+		{"undeclared path", "GET", "/v1/owners", "", "", ""},
+		{"outside the base path", "GET", "/pets?limit=abc", "", "", ""},
+		{"query parameter type", "GET", "/v1/pets?limit=abc", "", "", `query parameter "limit": "abc" is not a number`},
+		{"query parameter minimum", "GET", "/v1/pets?limit=0", "", "", `query parameter "limit": must be greater than or equal to 1`},
+		{"query parameter maximum", "GET", "/v1/pets?limit=101", "", "", `query parameter "limit": must be less than or equal to 100`},
+		{"path parameter", "GET", "/v1/pets/abc", "", "", `path parameter "petId": "abc" is not a number`},
+		{"valid path parameter", "DELETE", "/v1/pets/42", "", "", ""},
+		{"valid body", "POST", "/v1/pets", "application/json", `{"name":"Rex","tag":"dog"}`, ""},
+		{"missing body", "POST", "/v1/pets", "application/json", ``, `request body: missing required body`},
+		{"invalid JSON", "POST", "/v1/pets", "application/json", `{`, `request body: invalid JSON: unexpected EOF`},
+		{"unsupported content type", "POST", "/v1/pets", "text/plain", `Rex`, `request body: unsupported content type "text/plain"`},
+		{"missing property", "POST", "/v1/pets", "application/json", `{"tag":"dog"}`, `request body: missing required property "name"`},
+		{"property type", "POST", "/v1/pets", "application/json", `{"name":42}`, `request body at "/name": must be of type string`},
+		{"property length", "POST", "/v1/pets", "application/json", `{"name":""}`, `request body at "/name": must be at least 1 characters long`},
+		{"additional property", "POST", "/v1/pets", "application/json", `{"name":"Rex","age":3}`, `request body: unexpected property "age"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+
+			err := d.Validate(req)
+			if tc.want == "" {
+				if err != nil {
+					t.Errorf("expected no error, found %v", err)
+				}
+				return
+			}
+
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("expected a ValidationError, found %v", err)
+			}
+			if have := err.Error(); have != tc.want {
+				t.Errorf("expected error %q, found %q", tc.want, have)
+			}
+		})
+	}
+
+	t.Run("leaves the body readable", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/v1/pets", strings.NewReader(`{"name":"Rex"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if err := d.Validate(req); err != nil {
+			t.Fatalf("validating: %v", err)
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		if want, have := `{"name":"Rex"}`, string(body); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+	})
+}
+
+func TestValidateValue(t *testing.T) {
+	testCases := [...]struct {
+		schema string
+		value  string
+		want   string
+	}{
+		{`{"type": "string", "nullable": true}`, `null`, ""},
+		{`{"type": "string"}`, `null`, "must not be null"},
+		{`{"type": "string", "enum": ["a", "b"]}`, `"c"`, "must be one of the enumerated values"},
+		{`{"type": "string", "pattern": "^[a-z]+$"}`, `"ABC"`, `must match the pattern "^[a-z]+$"`},
+		{`{"type": "string", "format": "date-time"}`, `"yesterday"`, "must be a valid date-time"},
+		{`{"type": "string", "format": "uuid"}`, `"00000000-0000-0000-0000-000000000000"`, ""},
+		{`{"type": "integer"}`, `1.5`, "must be an integer"},
+		{`{"type": "number", "minimum": 1, "exclusiveMinimum": true}`, `1`, "must be greater than 1"},
+		{`{"type": "number", "multipleOf": 0.5}`, `1.25`, "must be a multiple of 0.5"},
+		{`{"type": "boolean"}`, `"true"`, "must be of type boolean"},
+		{`{"type": "array", "items": {"type": "integer"}, "maxItems": 2}`, `[1, 2, 3]`, "must have at most 2 items"},
+		{`{"type": "array", "uniqueItems": true}`, `[1, 1]`, "must have unique items"},
+		{`{"type": "array", "items": {"type": "integer"}}`, `[1, "a"]`, `at "/1": must be of type integer`},
+		{`{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `true`, "must match at least one schema of anyOf"},
+		{`{"oneOf": [{"type": "number"}, {"type": "integer"}]}`, `1`, "must match exactly one schema of oneOf, matched 2"},
+		{`{"allOf": [{"required": ["a"]}, {"required": ["b"]}]}`, `{"a": 1}`, `missing required property "b"`},
+		{`{"additionalProperties": {"type": "integer"}}`, `{"a/b": "x"}`, `at "/a~1b": must be of type integer`},
+		{`{"required": ["id", "name"], "properties": {"id": {"type": "integer", "readOnly": true}, "name": {"type": "string"}}}`, `{"name": "a"}`, ""},
+		{`{"required": ["id", "name"], "properties": {"id": {"type": "integer", "readOnly": true}, "name": {"type": "string"}}}`, `{"id": 1}`, `missing required property "name"`},
+		{`{"required": ["password"], "properties": {"password": {"type": "string", "writeOnly": true}}}`, `{}`, `missing required property "password"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.schema+" "+tc.value, func(t *testing.T) {
+			d := &Document{Paths: map[string]PathItem{"/": {Post: &Operation{
+				RequestBody: &RequestBody{Content: map[string]MediaType{
+					"application/json": {Schema: mustSchema(t, tc.schema)},
+				}},
+			}}}}
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.value))
+			req.Header.Set("Content-Type", "application/json")
+
+			err := d.Validate(req)
+			if tc.want == "" {
+				if err != nil {
+					t.Errorf("expected no error, found %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasSuffix(err.Error(), tc.want) {
+				t.Errorf("expected error ending with %q, found %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io/ioutil"
+
+	"github.com/pierreprinetti/apimock/openapi"
+	"github.com/pierreprinetti/apimock/store"
+)
+
+// loadSpec saves a mock response for every operation of the OpenAPI
+// document file, replacing the saved responses with the same paths.
+// The parsed document is returned.
+func loadSpec(resources loader, file string) (*openapi.Document, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := openapi.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	mocks, err := doc.Mocks()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]store.Record, len(mocks))
+	for i, m := range mocks {
+		records[i] = store.Record{
+			Method:      m.Method,
+			Path:        m.Path,
+			ContentType: m.ContentType,
+			Body:        m.Body,
+			Status:      m.Status,
+		}
+	}
+
+	return doc, resources.Load(records)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pierreprinetti/apimock/store"
+)
+
+func TestLoadSpec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apimock")
+	if err != nil {
+		t.Fatalf("creating the temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "openapi.json")
+	if err := ioutil.WriteFile(file, []byte(`{
+		"openapi": "3.0.3",
+		"servers": [{"url": "/v1"}],
+		"paths": {
+			"/users/{id}": {
+				"get": {"responses": {"200": {"content": {"application/json": {"example": {"id": 1}}}}}},
+				"delete": {"responses": {"204": {"description": "Deleted"}}}
+			}
+		}
+	}`), 0644); err != nil {
+		t.Fatalf("writing the document: %v", err)
+	}
+
+	t.Run("saves the mocks", func(t *testing.T) {
+		resources := store.New()
+		if _, err := loadSpec(resources, file); err != nil {
+			t.Fatalf("loading: %v", err)
+		}
+
+		h := newRouter(resources, nil)
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/users/42", nil))
+		if want, have := `{"id": 1}`, rec.Body.String(); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+		if want, have := "application/json", rec.Header().Get("Content-Type"); want != have {
+			t.Errorf("expected content type %q, found %q", want, have)
+		}
+
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("DELETE", "/v1/users/42", nil))
+		if want, have := 204, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+	})
+
+	t.Run("fails on invalid documents", func(t *testing.T) {
+		invalid := filepath.Join(dir, "invalid.json")
+		if err := ioutil.WriteFile(invalid, []byte(`{"swagger": "2.0"}`), 0644); err != nil {
+			t.Fatalf("writing the document: %v", err)
+		}
+		if _, err := loadSpec(store.New(), invalid); err == nil {
+			t.Error("expected an error, found none")
+		}
+	})
+}
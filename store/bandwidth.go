@@ -0,0 +1,125 @@
+package store
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bandwidth is the rate at which the response bodies are sent. Its zero
+// value defers to the bandwidth of the store, which is unlimited unless
+// configured otherwise.
+type Bandwidth struct {
+	set bool
+
+	// rate is in bytes per second. Zero is unlimited.
+	rate int64
+}
+
+// ParseBandwidth parses a rate in bytes per second, optionally followed by
+// the suffix "k" (1024 bytes per second) or "M" (1048576 bytes per second),
+// such as "512", "64k" or "1M". A rate of "0" is unlimited.
+// The empty string is the zero Bandwidth.
+func ParseBandwidth(s string) (Bandwidth, error) {
+	if s == "" {
+		return Bandwidth{}, nil
+	}
+
+	value, unit := s, int64(1)
+	switch {
+	case strings.HasSuffix(s, "k"):
+		value, unit = s[:len(s)-1], 1<<10
+	case strings.HasSuffix(s, "M"):
+		value, unit = s[:len(s)-1], 1<<20
+	}
+
+	rate, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || rate < 0 || rate > math.MaxInt64/unit {
+		return Bandwidth{}, fmt.Errorf("invalid bandwidth %q", s)
+	}
+
+	return Bandwidth{set: true, rate: rate * unit}, nil
+}
+
+func (b Bandwidth) String() string {
+	switch {
+	case !b.set:
+		return ""
+	case b.rate != 0 && b.rate%(1<<20) == 0:
+		return strconv.FormatInt(b.rate>>20, 10) + "M"
+	case b.rate != 0 && b.rate%(1<<10) == 0:
+		return strconv.FormatInt(b.rate>>10, 10) + "k"
+	default:
+		return strconv.FormatInt(b.rate, 10)
+	}
+}
+
+// or returns b, or def if b is the zero Bandwidth.
+func (b Bandwidth) or(def Bandwidth) Bandwidth {
+	if !b.set {
+		return def
+	}
+	return b
+}
+
+// writer returns a ResponseWriter that throttles the writes to rw, or rw
+// itself if the bandwidth is unlimited or if the response to the request has
+// no body.
+func (b Bandwidth) writer(rw http.ResponseWriter, req *http.Request) http.ResponseWriter {
+	if b.rate == 0 || req.Method == http.MethodHead {
+		return rw
+	}
+	return throttledWriter{ResponseWriter: rw, req: req, rate: b.rate}
+}
+
+// throttledWriter is a ResponseWriter that sends the body in chunks, so that
+// it is written at the given rate.
+type throttledWriter struct {
+	http.ResponseWriter
+
+	req  *http.Request
+	rate int64
+}
+
+// chunksPerSecond sets the granularity of the throttling.
+const chunksPerSecond = 10
+
+func (tw throttledWriter) Write(b []byte) (int, error) {
+	chunkSize := int(tw.rate / chunksPerSecond)
+	if chunkSize <= 0 {
+		chunkSize = 1
+	}
+
+	flusher, _ := tw.ResponseWriter.(http.Flusher)
+
+	var written int
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > chunkSize {
+			chunk = chunk[:chunkSize]
+		}
+
+		n, err := tw.ResponseWriter.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		b = b[n:]
+
+		timer := time.NewTimer(time.Duration(n) * time.Second / time.Duration(tw.rate))
+		select {
+		case <-timer.C:
+		case <-tw.req.Context().Done():
+			timer.Stop()
+			return written, tw.req.Context().Err()
+		}
+	}
+
+	return written, nil
+}
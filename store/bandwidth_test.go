@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseBandwidth(t *testing.T) {
+	testCases := [...]struct {
+		value string
+		want  string
+		ok    bool
+	}{
+		{"", "", true},
+		{"0", "0", true},
+		{"512", "512", true},
+		{"2048", "2k", true},
+		{"64k", "64k", true},
+		{"1024k", "1M", true},
+		{"1M", "1M", true},
+		{"-1", "", false},
+		{"1G", "", false},
+		{"9000000000000M", "", false},
+		{"9007199254740992k", "", false},
+		{"8796093022207M", "8796093022207M", true},
+		{"fast", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			b, err := ParseBandwidth(tc.value)
+			if ok := err == nil; ok != tc.ok {
+				t.Fatalf("expected ok %v, found error %v", tc.ok, err)
+			}
+			if have := b.String(); have != tc.want {
+				t.Errorf("expected %q, found %q", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestThrottledWriter(t *testing.T) {
+	t.Run("sends the body at the given rate", func(t *testing.T) {
+		e := entry{body: []byte("01234567890123456789"), bandwidth: Bandwidth{set: true, rate: 100}}
+
+		start := time.Now()
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+			t.Errorf("expected a transfer time of at least 200ms, found %v", elapsed)
+		}
+		if want, have := string(e.body), rec.Body.String(); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+		if !rec.Flushed {
+			t.Error("expected the chunks to be flushed")
+		}
+	})
+
+	t.Run("gives up on canceled requests", func(t *testing.T) {
+		e := entry{body: []byte("01234567890123456789"), bandwidth: Bandwidth{set: true, rate: 1}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+		if want, have := "0", rec.Body.String(); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+	})
+
+	t.Run("leaves HEAD requests alone", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		if rw := (Bandwidth{set: true, rate: 1}).writer(rec, httptest.NewRequest(http.MethodHead, "/", nil)); rw != http.ResponseWriter(rec) {
+			t.Errorf("expected the original writer, found %T", rw)
+		}
+	})
+
+	t.Run("leaves unlimited writers alone", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		if rw := (Bandwidth{set: true}).writer(rec, nil); rw != http.ResponseWriter(rec) {
+			t.Errorf("expected the original writer, found %T", rw)
+		}
+	})
+}
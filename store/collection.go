@@ -0,0 +1,72 @@
+package store
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// collection is the list of the entries stored right under a common path.
+type collection []entry
+
+// isChild reports whether the key is one path segment below the parent.
+// Keys bearing a query string are never children.
+func isChild(parent, key string) bool {
+	if strings.ContainsRune(key, '?') || !strings.HasPrefix(key, parent+"/") {
+		return false
+	}
+
+	name := key[len(parent)+1:]
+	return name != "" && !strings.ContainsRune(name, '/')
+}
+
+// lessKey orders keys by their last path segment, comparing numerically the
+// segments that are both integers; integers come before the other segments.
+// Keys with equivalent last segments are ordered by their full path, so that
+// the order is total.
+func lessKey(a, b string) bool {
+	lastA, lastB := a[strings.LastIndexByte(a, '/')+1:], b[strings.LastIndexByte(b, '/')+1:]
+
+	x, errA := strconv.Atoi(lastA)
+	y, errB := strconv.Atoi(lastB)
+	switch {
+	case errA == nil && errB == nil:
+		if x != y {
+			return x < y
+		}
+	case errA == nil:
+		return true
+	case errB == nil:
+		return false
+	case lastA != lastB:
+		return lastA < lastB
+	}
+
+	return a < b
+}
+
+// ServeHTTP sends a JSON array with the body of every entry in the collection.
+// Bodies that are valid JSON are embedded as they are; every other body is
+// encoded as a JSON string.
+func (c collection) ServeHTTP(rw http.ResponseWriter, _ *http.Request) {
+	items := make([]interface{}, len(c))
+	for i, e := range c {
+		if json.Valid(e.body) {
+			items[i] = json.RawMessage(e.body)
+		} else {
+			items[i] = string(e.body)
+		}
+	}
+
+	body, err := json.Marshal(items)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	rw.Write(body)
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsChild(t *testing.T) {
+	testCases := [...]struct {
+		parent string
+		key    string
+		want   bool
+	}{
+		{"/items", "/items/1", true},
+		{"/items", "/items/abc", true},
+		{"", "/items", true},
+		{"/items", "/items", false},
+		{"/items", "/items/", false},
+		{"/items", "/items/1/tags", false},
+		{"/items", "/itemsandmore", false},
+		{"/items", "/items/1?page=2", false},
+		{"/items", "/other/1", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%q in %q", tc.key, tc.parent), func(t *testing.T) {
+			if have := isChild(tc.parent, tc.key); have != tc.want {
+				t.Errorf("expected %v, found %v", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestLessKey(t *testing.T) {
+	testCases := [...]struct {
+		a, b string
+		want bool
+	}{
+		{"/items/2", "/items/10", true},
+		{"/items/10", "/items/2", false},
+		{"/items/a", "/items/b", true},
+		{"/items/10", "/items/b", true},
+		{"/items/b", "/items/10", false},
+		{"/items/1", "/items/01", false},
+		{"/items/01", "/items/1", true},
+		{"/a/1", "/b/1", true},
+		{"/b/1", "/a/1", false},
+		{"/a/1", "/a/1", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%q before %q", tc.a, tc.b), func(t *testing.T) {
+			if have := lessKey(tc.a, tc.b); have != tc.want {
+				t.Errorf("expected %v, found %v", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestCollectionServeHTTP(t *testing.T) {
+	testCases := [...]struct {
+		name       string
+		collection collection
+		want       string
+	}{
+		{
+			"embeds the JSON bodies",
+			collection{{body: []byte(`{"id":1}`)}, {body: []byte(`{"id":2}`)}},
+			`[{"id":1},{"id":2}]` + "\n",
+		},
+		{
+			"encodes the other bodies as strings",
+			collection{{body: []byte(`{"id":1}`)}, {body: []byte("not json")}},
+			`[{"id":1},"not json"]` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com/items", nil)
+			rw := httptest.NewRecorder()
+			tc.collection.ServeHTTP(rw, req)
+
+			if want, have := "application/json", rw.Header().Get("Content-Type"); have != want {
+				t.Errorf("expected content-type %q, found %q", want, have)
+			}
+			if have := rw.Body.String(); have != tc.want {
+				t.Errorf("expected body %q, found %q", tc.want, have)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrPreconditionFailed is returned when the conditional headers of a
+// request that modifies the store do not hold.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// etag returns the entity tag of the entry: the saved ETag header if any, or
+// a hash of the body.
+func (e entry) etag() string {
+	if etag := e.header.Get("ETag"); etag != "" {
+		return etag
+	}
+	return fmt.Sprintf(`"%x"`, sha1.Sum(e.body))
+}
+
+// lastModified returns the modification time of the entry: the saved
+// Last-Modified header if any, or the time it was saved.
+func (e entry) lastModified() time.Time {
+	if t, err := http.ParseTime(e.header.Get("Last-Modified")); err == nil {
+		return t
+	}
+	return e.modTime
+}
+
+// parseTags splits the value of an If-Match or If-None-Match header into
+// its entity tags.
+func parseTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// matchTag reports whether the etag is listed in the header value. The weak
+// comparison ignores the weakness indicator; the strong comparison requires
+// both tags to be strong.
+func matchTag(value, etag string, weak bool) bool {
+	for _, tag := range parseTags(value) {
+		if tag == "*" {
+			return true
+		}
+		if weak {
+			if strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+			continue
+		}
+		if tag == etag && !strings.HasPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// notModifiedSince reports whether the entry was not modified after the
+// date in the header value. Invalid dates are ignored.
+func notModifiedSince(e entry, value string) (bool, bool) {
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return false, false
+	}
+	return !e.lastModified().Truncate(time.Second).After(t), true
+}
+
+// evaluatePreconditions evaluates the conditional headers of the request
+// against the entry, in the order defined by RFC 7232, section 6. The
+// exists boolean is false if no entry is saved at the request target.
+// It returns 304 Not Modified or 412 Precondition Failed if a condition
+// does not hold, zero otherwise.
+func evaluatePreconditions(req *http.Request, e entry, exists bool) int {
+	safe := req.Method == http.MethodGet || req.Method == http.MethodHead
+
+	if value := req.Header.Get("If-Match"); value != "" {
+		if !exists || !matchTag(value, e.etag(), false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if value := req.Header.Get("If-Unmodified-Since"); value != "" && exists {
+		if ok, valid := notModifiedSince(e, value); valid && !ok {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if value := req.Header.Get("If-None-Match"); value != "" {
+		if exists && matchTag(value, e.etag(), true) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if value := req.Header.Get("If-Modified-Since"); value != "" && exists && safe {
+		if ok, valid := notModifiedSince(e, value); valid && ok {
+			return http.StatusNotModified
+		}
+	}
+
+	return 0
+}
+
+// checkPreconditions returns an error wrapping ErrPreconditionFailed if the
+// conditional headers of a request modifying the entry do not hold.
+func checkPreconditions(req *http.Request, e entry, exists bool) error {
+	if evaluatePreconditions(req, e, exists) != 0 {
+		return fmt.Errorf("%w: the saved entry does not match the conditional headers", ErrPreconditionFailed)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvaluatePreconditions(t *testing.T) {
+	modTime := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	e := entry{body: []byte("body"), modTime: modTime}
+	etag := e.etag()
+
+	before := modTime.Add(-time.Hour).Format(http.TimeFormat)
+	after := modTime.Add(time.Hour).Format(http.TimeFormat)
+
+	testCases := [...]struct {
+		name   string
+		method string
+		header map[string]string
+		exists bool
+		want   int
+	}{
+		{"no conditions", "GET", nil, true, 0},
+		{"If-None-Match matches on GET", "GET", map[string]string{"If-None-Match": etag}, true, 304},
+		{"If-None-Match matches weakly on GET", "GET", map[string]string{"If-None-Match": `"other", W/` + etag}, true, 304},
+		{"If-None-Match does not match", "GET", map[string]string{"If-None-Match": `"other"`}, true, 0},
+		{"If-None-Match matches on PUT", "PUT", map[string]string{"If-None-Match": etag}, true, 412},
+		{"If-None-Match * prevents overwriting", "PUT", map[string]string{"If-None-Match": "*"}, true, 412},
+		{"If-None-Match * allows creating", "PUT", map[string]string{"If-None-Match": "*"}, false, 0},
+		{"If-None-Match takes precedence over If-Modified-Since", "GET", map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": after}, true, 0},
+		{"If-Modified-Since not modified", "GET", map[string]string{"If-Modified-Since": after}, true, 304},
+		{"If-Modified-Since same second", "GET", map[string]string{"If-Modified-Since": modTime.Format(http.TimeFormat)}, true, 304},
+		{"If-Modified-Since modified", "GET", map[string]string{"If-Modified-Since": before}, true, 0},
+		{"If-Modified-Since ignored on PUT", "PUT", map[string]string{"If-Modified-Since": after}, true, 0},
+		{"If-Match matches", "PUT", map[string]string{"If-Match": etag}, true, 0},
+		{"If-Match does not match", "PUT", map[string]string{"If-Match": `"other"`}, true, 412},
+		{"If-Match requires strong tags", "PUT", map[string]string{"If-Match": "W/" + etag}, true, 412},
+		{"If-Match requires an entry", "PUT", map[string]string{"If-Match": "*"}, false, 412},
+		{"If-Unmodified-Since holds", "DELETE", map[string]string{"If-Unmodified-Since": after}, true, 0},
+		{"If-Unmodified-Since fails", "DELETE", map[string]string{"If-Unmodified-Since": before}, true, 412},
+		{"If-Match takes precedence over If-Unmodified-Since", "PUT", map[string]string{"If-Match": etag, "If-Unmodified-Since": before}, true, 0},
+		{"invalid dates are ignored", "GET", map[string]string{"If-Modified-Since": "yesterday"}, true, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/", nil)
+			for name, value := range tc.header {
+				req.Header.Set(name, value)
+			}
+			if have := evaluatePreconditions(req, e, tc.exists); have != tc.want {
+				t.Errorf("expected %d, found %d", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestEntryValidators(t *testing.T) {
+	t.Run("prefers the saved ETag", func(t *testing.T) {
+		e := entry{body: []byte("body"), header: http.Header{"Etag": {`"v1"`}}}
+		if want, have := `"v1"`, e.etag(); want != have {
+			t.Errorf("expected ETag %q, found %q", want, have)
+		}
+	})
+
+	t.Run("hashes the body", func(t *testing.T) {
+		a, b := entry{body: []byte("one")}, entry{body: []byte("two")}
+		if a.etag() == b.etag() {
+			t.Errorf("expected different ETags, found %q", a.etag())
+		}
+	})
+
+	t.Run("prefers the saved Last-Modified", func(t *testing.T) {
+		want := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+		e := entry{modTime: time.Now(), header: http.Header{"Last-Modified": {want.Format(http.TimeFormat)}}}
+		if have := e.lastModified(); !have.Equal(want) {
+			t.Errorf("expected Last-Modified %v, found %v", want, have)
+		}
+	})
+}
+
+func TestConditionalRequests(t *testing.T) {
+	s := New()
+
+	req := httptest.NewRequest("PUT", "/doc", strings.NewReader("v1"))
+	if err := s.Set(http.MethodGet, "/doc", req); err != nil {
+		t.Fatalf("setting: %v", err)
+	}
+	etag := s.entries[key{http.MethodGet, "/doc"}].etag()
+
+	t.Run("GET is not modified", func(t *testing.T) {
+		h, _ := s.Get(http.MethodGet, "/doc")
+		req := httptest.NewRequest("GET", "/doc", nil)
+		req.Header.Set("If-None-Match", etag)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if want, have := 304, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+		if have := rec.Body.String(); have != "" {
+			t.Errorf("expected no body, found %q", have)
+		}
+	})
+
+	t.Run("PUT with a stale ETag fails", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/doc", strings.NewReader("v2"))
+		req.Header.Set("If-Match", `"stale"`)
+		if err := s.Set(http.MethodGet, "/doc", req); !errors.Is(err, ErrPreconditionFailed) {
+			t.Errorf("expected error %v, found %v", ErrPreconditionFailed, err)
+		}
+	})
+
+	t.Run("PUT with the current ETag succeeds", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/doc", strings.NewReader("v2"))
+		req.Header.Set("If-Match", etag)
+		if err := s.Set(http.MethodGet, "/doc", req); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("DELETE with a stale ETag fails", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/doc", nil)
+		req.Header.Set("If-Match", etag)
+		if _, err := s.Del(http.MethodGet, "/doc", req); !errors.Is(err, ErrPreconditionFailed) {
+			t.Errorf("expected error %v, found %v", ErrPreconditionFailed, err)
+		}
+	})
+}
@@ -1,12 +1,87 @@
 package store
 
 import (
+	"io/ioutil"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type entry struct {
 	contentType string
 	body        []byte
+
+	// status is the response status code. Zero means 200 OK.
+	status int
+
+	// header holds the response headers other than Content-Type.
+	header http.Header
+
+	// query is the policy for matching the query string of the requests.
+	query QueryMatching
+
+	// modTime is the time the entry was last modified.
+	modTime time.Time
+
+	// latency is the delay applied before sending the response.
+	latency Latency
+
+	// bandwidth is the rate at which the body is sent.
+	bandwidth Bandwidth
+
+	// next holds the responses following this one in a sequence.
+	next []entry
+
+	// cycle makes the sequence start over after its last response, instead
+	// of repeating it.
+	cycle bool
+
+	// calls counts the requests answered by the sequence. It is shared by
+	// the copies of the entry.
+	calls *uint64
+}
+
+// entryFromRequest builds an entry out of the request body and headers.
+// An error is returned if the request body io.Reader is not readable, or if
+// an apimock header is invalid.
+func entryFromRequest(req *http.Request, override, def string) (entry, error) {
+	status, err := statusFromRequest(req)
+	if err != nil {
+		return entry{}, err
+	}
+
+	query, err := queryMatchingFromRequest(req)
+	if err != nil {
+		return entry{}, err
+	}
+
+	latency, err := latencyFromRequest(req)
+	if err != nil {
+		return entry{}, err
+	}
+
+	bandwidth, err := bandwidthFromRequest(req)
+	if err != nil {
+		return entry{}, err
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return entry{}, err
+	}
+
+	return entry{
+		contentType: contentTypeFromRequest(req, override, def),
+		body:        body,
+		status:      status,
+		header:      headerFromRequest(req),
+		query:       query,
+		modTime:     time.Now(),
+		latency:     latency,
+		bandwidth:   bandwidth,
+	}, nil
 }
 
 func contentTypeFromRequest(req *http.Request, override, def string) string {
@@ -23,7 +98,81 @@ func contentTypeFromRequest(req *http.Request, override, def string) string {
 	return contentType
 }
 
-func (e entry) ServeHTTP(rw http.ResponseWriter, _ *http.Request) {
+// exposeHeaders lists the validator and range headers, along with the saved
+// response headers and the ones already exposed by the caller, so that they
+// are readable by cross-origin scripts.
+func (e entry) exposeHeaders(exposed string) string {
+	names := []string{"Content-Range", "ETag"}
+	seen := map[string]bool{"Content-Range": true, "Etag": true}
+	add := func(name string) {
+		if canonical := http.CanonicalHeaderKey(name); !seen[canonical] {
+			seen[canonical] = true
+			names = append(names, name)
+		}
+	}
+
+	for name := range e.header {
+		add(name)
+	}
+	for _, name := range strings.Split(exposed, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			add(name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func (e entry) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if !e.latency.wait(req) {
+		return
+	}
+	e.reply(e.bandwidth.writer(rw, req), req)
+}
+
+// reply sends the response without the latency and the bandwidth.
+func (e entry) reply(rw http.ResponseWriter, req *http.Request) {
+
+	for name, values := range e.header {
+		rw.Header()[name] = append([]string(nil), values...)
+	}
+	rw.Header().Set("Access-Control-Expose-Headers", e.exposeHeaders(rw.Header().Get("Access-Control-Expose-Headers")))
+
+	// Validators only make sense for successful responses
+	if e.status == 0 || e.status == http.StatusOK {
+		rw.Header().Set("ETag", e.etag())
+		if t := e.lastModified(); !t.IsZero() {
+			rw.Header().Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+		}
+
+		rw.Header().Set("Accept-Ranges", "bytes")
+
+		if req.Method == http.MethodGet || req.Method == http.MethodHead {
+			if status := evaluatePreconditions(req, e, true); status != 0 {
+				rw.WriteHeader(status)
+				return
+			}
+
+			if value := req.Header.Get("Range"); value != "" && e.rangeApplies(req) {
+				if e.serveRanges(rw, value) {
+					return
+				}
+			}
+		}
+	}
+
 	rw.Header().Set("Content-Type", e.contentType)
+	if bodyAllowed(e.status) {
+		rw.Header().Set("Content-Length", strconv.Itoa(len(e.body)))
+	}
+	if e.status != 0 {
+		rw.WriteHeader(e.status)
+	}
 	rw.Write(e.body)
 }
+
+// bodyAllowed reports whether a response with the given status code can
+// have a body. Zero means 200 OK.
+func bodyAllowed(status int) bool {
+	return (status < 100 || status > 199) && status != http.StatusNoContent && status != http.StatusNotModified
+}
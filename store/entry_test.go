@@ -102,6 +102,22 @@ func TestEntryServeHTTP(t *testing.T) {
 			return nil
 		}
 	}
+	hasHeader := func(name, want string) checkFunc {
+		return func(rw *httptest.ResponseRecorder) error {
+			if have := rw.Result().Header.Get(name); have != want {
+				return fmt.Errorf("expected header %s %q, found %q", name, want, have)
+			}
+			return nil
+		}
+	}
+	hasStatus := func(want int) checkFunc {
+		return func(rw *httptest.ResponseRecorder) error {
+			if have := rw.Code; have != want {
+				return fmt.Errorf("expected status %d, found %d", want, have)
+			}
+			return nil
+		}
+	}
 	hasBody := func(want string) checkFunc {
 		return func(rw *httptest.ResponseRecorder) error {
 			body, err := ioutil.ReadAll(rw.Result().Body)
@@ -120,6 +136,8 @@ func TestEntryServeHTTP(t *testing.T) {
 		name        string
 		contentType string
 		body        string
+		status      int
+		header      http.Header
 		checks      []checkFunc
 	}{
 		{
@@ -132,6 +150,47 @@ func TestEntryServeHTTP(t *testing.T) {
 			body:   "this is the body",
 			checks: check(hasBody("this is the body")),
 		},
+		{
+			name:   "sets the content length",
+			body:   "this is the body",
+			checks: check(hasHeader("Content-Length", "16")),
+		},
+		{
+			name:   "sets no content length without body",
+			status: 204,
+			checks: check(hasHeader("Content-Length", "")),
+		},
+		{
+			name:   "defaults to 200",
+			checks: check(hasStatus(200)),
+		},
+		{
+			name:   "sends the status",
+			body:   "this is the body",
+			status: 503,
+			checks: check(
+				hasStatus(503),
+				hasBody("this is the body"),
+			),
+		},
+		{
+			name: "sends the headers",
+			header: http.Header{
+				"X-Total-Count": {"42"},
+				"Link":          {"</items?page=2>; rel=next"},
+			},
+			checks: check(
+				hasHeader("X-Total-Count", "42"),
+				hasHeader("Link", "</items?page=2>; rel=next"),
+				hasHeader("Access-Control-Expose-Headers", "Content-Range, ETag, Link, X-Total-Count"),
+			),
+		},
+		{
+			name:        "prefers its own content type",
+			contentType: "our/contenttype",
+			header:      http.Header{"Content-Type": {"their/contenttype"}},
+			checks:      check(hasContentType("our/contenttype")),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -139,6 +198,8 @@ func TestEntryServeHTTP(t *testing.T) {
 			e := entry{
 				contentType: tc.contentType,
 				body:        []byte(tc.body),
+				status:      tc.status,
+				header:      tc.header,
 			}
 
 			req := httptest.NewRequest("GET", "http://example.com/foo", nil)
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The request headers that configure the entries saved in the store.
+const (
+	// StatusHeader holds the status code of the saved response.
+	StatusHeader = "X-Apimock-Status"
+
+	// MethodHeader holds the HTTP method that the saved response answers to.
+	// Requests without it configure the GET response.
+	MethodHeader = "X-Apimock-Method"
+
+	// QueryHeader holds the query matching policy of the saved response. See
+	// ParseQueryMatching for the accepted values.
+	QueryHeader = "X-Apimock-Query"
+
+	// LatencyHeader holds the delay applied before sending the saved
+	// response. See ParseLatency for the accepted values.
+	LatencyHeader = "X-Apimock-Latency"
+
+	// BandwidthHeader holds the rate at which the saved response body is
+	// sent. See ParseBandwidth for the accepted values.
+	BandwidthHeader = "X-Apimock-Bandwidth"
+
+	// SequenceHeader appends the saved response to the ones already saved
+	// for the same key, rather than replacing them. Successive requests are
+	// answered with the successive responses; after the last one, the value
+	// "last" repeats it forever, while "cycle" starts over. On a DELETE
+	// request, the value "rewind" restarts the sequence instead of deleting
+	// it.
+	SequenceHeader = "X-Apimock-Sequence"
+
+	// HeaderPrefix prefixes the name of the headers of the saved response.
+	// For example, "X-Apimock-Header-Cache-Control: no-cache" saves the
+	// response header "Cache-Control: no-cache".
+	HeaderPrefix = "X-Apimock-Header-"
+)
+
+// ErrInvalidHeader is returned when a request bears an apimock header with an
+// invalid value.
+var ErrInvalidHeader = errors.New("invalid header")
+
+func invalidHeader(name, value string) error {
+	return fmt.Errorf("%w %s: %q", ErrInvalidHeader, name, value)
+}
+
+// statusFromRequest parses the status code requested for the saved response.
+// It returns zero if no status code was requested.
+func statusFromRequest(req *http.Request) (int, error) {
+	value := req.Header.Get(StatusHeader)
+	if value == "" {
+		return 0, nil
+	}
+
+	status, err := strconv.Atoi(value)
+	if err != nil || status < 100 || status > 599 {
+		return 0, invalidHeader(StatusHeader, value)
+	}
+
+	return status, nil
+}
+
+// headerFromRequest collects the headers requested for the saved response.
+// It returns nil if no header was requested.
+func headerFromRequest(req *http.Request) http.Header {
+	var header http.Header
+	for name, values := range req.Header {
+		name = http.CanonicalHeaderKey(name)
+		if !strings.HasPrefix(name, HeaderPrefix) || len(name) == len(HeaderPrefix) {
+			continue
+		}
+
+		if header == nil {
+			header = make(http.Header)
+		}
+		header[name[len(HeaderPrefix):]] = append([]string(nil), values...)
+	}
+	return header
+}
+
+// queryMatchingFromRequest parses the query matching policy requested for
+// the saved response.
+func queryMatchingFromRequest(req *http.Request) (QueryMatching, error) {
+	value := req.Header.Get(QueryHeader)
+
+	m, err := ParseQueryMatching(value)
+	if err != nil {
+		return QueryMatching{}, invalidHeader(QueryHeader, value)
+	}
+
+	return m, nil
+}
+
+// latencyFromRequest parses the latency requested for the saved response.
+func latencyFromRequest(req *http.Request) (Latency, error) {
+	value := req.Header.Get(LatencyHeader)
+
+	l, err := ParseLatency(value)
+	if err != nil {
+		return Latency{}, invalidHeader(LatencyHeader, value)
+	}
+
+	return l, nil
+}
+
+// bandwidthFromRequest parses the bandwidth requested for the saved response.
+func bandwidthFromRequest(req *http.Request) (Bandwidth, error) {
+	value := req.Header.Get(BandwidthHeader)
+
+	b, err := ParseBandwidth(value)
+	if err != nil {
+		return Bandwidth{}, invalidHeader(BandwidthHeader, value)
+	}
+
+	return b, nil
+}
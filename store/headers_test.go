@@ -0,0 +1,67 @@
+package store
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestStatusFromRequest(t *testing.T) {
+	testCases := [...]struct {
+		name   string
+		header string
+		want   int
+		err    error
+	}{
+		{"no header is zero", "", 0, nil},
+		{"parses the status", "503", 503, nil},
+		{"rejects non-numbers", "teapot", 0, ErrInvalidHeader},
+		{"rejects out of range codes", "600", 0, ErrInvalidHeader},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("PUT", "/", nil)
+			req.Header.Set(StatusHeader, tc.header)
+
+			have, err := statusFromRequest(req)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected error %v, found %v", tc.err, err)
+			}
+			if have != tc.want {
+				t.Errorf("expected status %d, found %d", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestHeaderFromRequest(t *testing.T) {
+	t.Run("collects the prefixed headers", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/", nil)
+		req.Header.Set("X-Apimock-Header-ETag", `"abc"`)
+		req.Header.Add("x-apimock-header-link", "</items?page=2>; rel=next")
+		req.Header.Add("X-Apimock-Header-Link", "</items?page=9>; rel=last")
+		req.Header.Set("X-Total-Count", "ignored")
+
+		have := headerFromRequest(req)
+
+		if want := 2; len(have) != want {
+			t.Errorf("expected %d headers, found %d", want, len(have))
+		}
+		if want := `"abc"`; have.Get("ETag") != want {
+			t.Errorf("expected ETag %q, found %q", want, have.Get("ETag"))
+		}
+		if want := 2; len(have["Link"]) != want {
+			t.Errorf("expected %d Link values, found %d", want, len(have["Link"]))
+		}
+	})
+
+	t.Run("is nil without prefixed headers", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/", nil)
+		req.Header.Set("X-Apimock-Header-", "no name")
+
+		if have := headerFromRequest(req); have != nil {
+			t.Errorf("expected nil, found %v", have)
+		}
+	})
+}
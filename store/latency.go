@@ -0,0 +1,128 @@
+package store
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type latencyKind int
+
+const (
+	latencyUnset latencyKind = iota
+	latencyFixed
+	latencyUniform
+	latencyNormal
+)
+
+// Latency is the distribution of an artificial delay applied before
+// sending a response. Its zero value defers to the latency of the store,
+// which is no delay unless configured otherwise.
+type Latency struct {
+	kind latencyKind
+
+	// a is the fixed delay, the lower bound of the uniform distribution, or
+	// the mean of the normal distribution.
+	a time.Duration
+
+	// b is the upper bound of the uniform distribution, or the standard
+	// deviation of the normal distribution.
+	b time.Duration
+}
+
+// ParseLatency parses a latency. Accepted values are:
+//   - a duration, such as "200ms", for a fixed delay
+//   - two durations separated by a dash, such as "100ms-500ms", for a
+//     delay uniformly distributed between them
+//   - two durations separated by a tilde, such as "300ms~50ms", for a delay
+//     normally distributed with the given mean and standard deviation
+//
+// The empty string is the zero Latency.
+func ParseLatency(s string) (Latency, error) {
+	if s == "" {
+		return Latency{}, nil
+	}
+
+	for _, kind := range [...]struct {
+		sep  string
+		kind latencyKind
+	}{
+		{"-", latencyUniform},
+		{"~", latencyNormal},
+	} {
+		if i := strings.Index(s, kind.sep); i >= 0 {
+			a, errA := time.ParseDuration(s[:i])
+			b, errB := time.ParseDuration(s[i+1:])
+			if errA != nil || errB != nil || a < 0 || b < 0 || (kind.kind == latencyUniform && b < a) {
+				return Latency{}, fmt.Errorf("invalid latency %q", s)
+			}
+			return Latency{kind: kind.kind, a: a, b: b}, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return Latency{}, fmt.Errorf("invalid latency %q", s)
+	}
+
+	return Latency{kind: latencyFixed, a: d}, nil
+}
+
+func (l Latency) String() string {
+	switch l.kind {
+	case latencyFixed:
+		return l.a.String()
+	case latencyUniform:
+		return l.a.String() + "-" + l.b.String()
+	case latencyNormal:
+		return l.a.String() + "~" + l.b.String()
+	default:
+		return ""
+	}
+}
+
+// or returns l, or def if l is the zero Latency.
+func (l Latency) or(def Latency) Latency {
+	if l.kind == latencyUnset {
+		return def
+	}
+	return l
+}
+
+// duration draws a delay from the distribution.
+func (l Latency) duration() time.Duration {
+	switch l.kind {
+	case latencyFixed:
+		return l.a
+	case latencyUniform:
+		return l.a + time.Duration(rand.Int63n(int64(l.b-l.a)+1))
+	case latencyNormal:
+		if d := l.a + time.Duration(rand.NormFloat64()*float64(l.b)); d > 0 {
+			return d
+		}
+		return 0
+	default:
+		return 0
+	}
+}
+
+// wait sleeps for a delay drawn from the distribution. It returns false if
+// the request was canceled in the meantime.
+func (l Latency) wait(req *http.Request) bool {
+	d := l.duration()
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-req.Context().Done():
+		return false
+	}
+}
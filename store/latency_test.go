@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseLatency(t *testing.T) {
+	testCases := [...]struct {
+		value string
+		want  string
+		ok    bool
+	}{
+		{"", "", true},
+		{"0", "0s", true},
+		{"200ms", "200ms", true},
+		{"100ms-500ms", "100ms-500ms", true},
+		{"300ms~50ms", "300ms~50ms", true},
+		{"500ms-100ms", "", false},
+		{"-200ms", "", false},
+		{"1s~", "", false},
+		{"slow", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			l, err := ParseLatency(tc.value)
+			if ok := err == nil; ok != tc.ok {
+				t.Fatalf("expected ok %v, found error %v", tc.ok, err)
+			}
+			if have := l.String(); have != tc.want {
+				t.Errorf("expected %q, found %q", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestLatencyDuration(t *testing.T) {
+	t.Run("fixed", func(t *testing.T) {
+		l := Latency{kind: latencyFixed, a: 200 * time.Millisecond}
+		if have := l.duration(); have != 200*time.Millisecond {
+			t.Errorf("expected 200ms, found %v", have)
+		}
+	})
+
+	t.Run("uniform", func(t *testing.T) {
+		l := Latency{kind: latencyUniform, a: 100 * time.Millisecond, b: 500 * time.Millisecond}
+		for i := 0; i < 100; i++ {
+			if have := l.duration(); have < l.a || have > l.b {
+				t.Fatalf("expected a duration between %v and %v, found %v", l.a, l.b, have)
+			}
+		}
+	})
+
+	t.Run("normal is never negative", func(t *testing.T) {
+		l := Latency{kind: latencyNormal, a: time.Millisecond, b: time.Second}
+		for i := 0; i < 100; i++ {
+			if have := l.duration(); have < 0 {
+				t.Fatalf("expected a positive duration, found %v", have)
+			}
+		}
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		if have := (Latency{}).duration(); have != 0 {
+			t.Errorf("expected no delay, found %v", have)
+		}
+	})
+}
+
+func TestLatencyWait(t *testing.T) {
+	t.Run("delays the response", func(t *testing.T) {
+		e := entry{body: []byte("hello"), latency: Latency{kind: latencyFixed, a: 50 * time.Millisecond}}
+
+		start := time.Now()
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("expected a delay of at least 50ms, found %v", elapsed)
+		}
+		if have := rec.Body.String(); have != "hello" {
+			t.Errorf("expected body %q, found %q", "hello", have)
+		}
+	})
+
+	t.Run("gives up on canceled requests", func(t *testing.T) {
+		e := entry{body: []byte("hello"), latency: Latency{kind: latencyFixed, a: time.Hour}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+		if have := rec.Body.Len(); have != 0 {
+			t.Errorf("expected no body, found %d bytes", have)
+		}
+	})
+}
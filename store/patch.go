@@ -0,0 +1,461 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The media types of the supported patch formats.
+const (
+	// MergePatchType is the media type of JSON Merge Patch (RFC 7396).
+	MergePatchType = "application/merge-patch+json"
+
+	// JSONPatchType is the media type of JSON Patch (RFC 6902).
+	JSONPatchType = "application/json-patch+json"
+)
+
+var (
+	// ErrUnsupportedPatch is returned when the patch media type is not
+	// supported.
+	ErrUnsupportedPatch = errors.New("unsupported patch media type")
+
+	// ErrInvalidPatch is returned when the patch document is malformed.
+	ErrInvalidPatch = errors.New("invalid patch")
+
+	// ErrPatchConflict is returned when the patch cannot be applied to the
+	// saved entry.
+	ErrPatchConflict = errors.New("patch conflict")
+)
+
+// isJSON reports whether the media type is JSON, or a JSON-based format.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// decodeJSON decodes a JSON document, preserving the numbers as they are.
+func decodeJSON(data []byte) (interface{}, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if d.More() {
+		return nil, errors.New("unexpected data after the JSON document")
+	}
+	return v, nil
+}
+
+// applyPatch applies the patch to the JSON document, according to the
+// patch media type. Plain JSON patches are applied as merge patches.
+func applyPatch(doc []byte, patch []byte, contentType string) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	target, err := decodeJSON(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: the saved entry is not valid JSON", ErrPatchConflict)
+	}
+
+	var result interface{}
+	switch mediaType {
+	case MergePatchType, "application/json":
+		p, err := decodeJSON(patch)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		result = mergePatch(target, p)
+	case JSONPatchType:
+		if result, err = jsonPatch(target, patch); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedPatch, contentType)
+	}
+
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(result); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// mergePatch applies a JSON Merge Patch, as defined in RFC 7396.
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+
+	for name, value := range p {
+		if value == nil {
+			delete(t, name)
+			continue
+		}
+		t[name] = mergePatch(t[name], value)
+	}
+
+	return t
+}
+
+// operation is a JSON Patch operation.
+type operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// jsonPatch applies a JSON Patch, as defined in RFC 6902.
+func jsonPatch(doc interface{}, patch []byte) (interface{}, error) {
+	var operations []operation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("%w: a JSON Patch is an array of operations", ErrInvalidPatch)
+	}
+
+	for i, op := range operations {
+		var err error
+		if doc, err = op.apply(doc); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+
+	return doc, nil
+}
+
+func (op operation) apply(doc interface{}) (interface{}, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: missing path", ErrInvalidPatch)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		if len(op.Value) == 0 {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+		if value, err = decodeJSON(op.Value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+	}
+
+	var from []string
+	switch op.Op {
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: missing from", ErrInvalidPatch)
+		}
+		if from, err = parsePointer(*op.From); err != nil {
+			return nil, err
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		return addValue(doc, path, value)
+	case "remove":
+		return removeValue(doc, path)
+	case "replace":
+		if _, err := getValue(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		if doc, err = removeValue(doc, path); err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, value)
+	case "move":
+		if isPrefix(from, path) && len(from) < len(path) {
+			return nil, fmt.Errorf("%w: cannot move a value into itself", ErrPatchConflict)
+		}
+		if value, err = getValue(doc, from); err != nil {
+			return nil, err
+		}
+		if doc, err = removeValue(doc, from); err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, value)
+	case "copy":
+		if value, err = getValue(doc, from); err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, clone(value))
+	case "test":
+		current, err := getValue(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equalJSON(current, value) {
+			return nil, fmt.Errorf("%w: test failed at %q", ErrPatchConflict, *op.Path)
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("%w: invalid pointer %q", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, tokens []string) bool {
+	if len(prefix) > len(tokens) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != tokens[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// arrayIndex parses an array index token. The upper bound is inclusive.
+// The "-" token, the index past the last element, is only accepted when
+// end is true: it is only a valid target for the add operation.
+func arrayIndex(token string, max int, end bool) (int, error) {
+	if token == "-" && end {
+		return max, nil
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrPatchConflict, token)
+	}
+	return i, nil
+}
+
+func notFound(token string) error {
+	return fmt.Errorf("%w: %q not found", ErrPatchConflict, token)
+}
+
+func getValue(doc interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			child, ok := node[token]
+			if !ok {
+				return nil, notFound(token)
+			}
+			doc = child
+		case []interface{}:
+			i, err := arrayIndex(token, len(node)-1, false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, notFound(token)
+		}
+	}
+	return doc, nil
+}
+
+func addValue(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	token, last := tokens[0], len(tokens) == 1
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		if last {
+			node[token] = value
+			return node, nil
+		}
+		child, ok := node[token]
+		if !ok {
+			return nil, notFound(token)
+		}
+		child, err := addValue(child, tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+
+	case []interface{}:
+		if last {
+			i, err := arrayIndex(token, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		i, err := arrayIndex(token, len(node)-1, false)
+		if err != nil {
+			return nil, err
+		}
+		child, err := addValue(node[i], tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = child
+		return node, nil
+
+	default:
+		return nil, notFound(token)
+	}
+}
+
+func removeValue(doc interface{}, tokens []string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole document", ErrPatchConflict)
+	}
+
+	token, last := tokens[0], len(tokens) == 1
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[token]
+		if !ok {
+			return nil, notFound(token)
+		}
+		if last {
+			delete(node, token)
+			return node, nil
+		}
+		child, err := removeValue(child, tokens[1:])
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+
+	case []interface{}:
+		i, err := arrayIndex(token, len(node)-1, false)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			return append(node[:i], node[i+1:]...), nil
+		}
+		child, err := removeValue(node[i], tokens[1:])
+		if err != nil {
+			return nil, err
+		}
+		node[i] = child
+		return node, nil
+
+	default:
+		return nil, notFound(token)
+	}
+}
+
+// clone returns a deep copy of a decoded JSON value.
+func clone(v interface{}) interface{} {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(node))
+		for name, value := range node {
+			c[name] = clone(value)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(node))
+		for i, value := range node {
+			c[i] = clone(value)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// equalJSON reports whether two decoded JSON values are equal. Numbers are
+// compared by value.
+func equalJSON(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for name, value := range x {
+			other, ok := y[name]
+			if !ok || !equalJSON(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equalJSON(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		f, errX := x.Float64()
+		g, errY := y.Float64()
+		if errX != nil || errY != nil {
+			return x == y
+		}
+		return f == g
+	default:
+		return a == b
+	}
+}
+
+// patch returns a copy of the entry, with the patch applied to its body.
+// The patch format is chosen according to the request Content-Type.
+func (e entry) patch(req *http.Request, patch []byte) (entry, error) {
+	if !isJSON(e.contentType) {
+		return entry{}, fmt.Errorf("%w: the saved entry is not JSON", ErrPatchConflict)
+	}
+
+	body, err := applyPatch(e.body, patch, req.Header.Get("Content-Type"))
+	if err != nil {
+		return entry{}, err
+	}
+
+	e.body = body
+	return e, nil
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	testCases := [...]struct {
+		name        string
+		doc         string
+		patch       string
+		contentType string
+		want        string
+		err         error
+	}{
+		{
+			name:        "merge patch replaces and removes members",
+			doc:         `{"a":"b","c":{"d":"e","f":"g"}}`,
+			patch:       `{"a":"z","c":{"f":null}}`,
+			contentType: MergePatchType,
+			want:        `{"a":"z","c":{"d":"e"}}`,
+		},
+		{
+			name:        "plain JSON is a merge patch",
+			doc:         `{"title":"Hello!","tags":["example"]}`,
+			patch:       `{"tags":["sample"],"phone":"+01-123-456-7890"}`,
+			contentType: "application/json; charset=utf-8",
+			want:        `{"phone":"+01-123-456-7890","tags":["sample"],"title":"Hello!"}`,
+		},
+		{
+			name:        "merge patch replaces non-objects",
+			doc:         `{"a":"b"}`,
+			patch:       `["c"]`,
+			contentType: MergePatchType,
+			want:        `["c"]`,
+		},
+		{
+			name:        "preserves numbers",
+			doc:         `{"big":12345678901234567890,"html":"<b>"}`,
+			patch:       `{}`,
+			contentType: MergePatchType,
+			want:        `{"big":12345678901234567890,"html":"<b>"}`,
+		},
+		{
+			name:        "JSON patch adds members and elements",
+			doc:         `{"foo":["bar","baz"]}`,
+			patch:       `[{"op":"add","path":"/foo/1","value":"qux"},{"op":"add","path":"/foo/-","value":"end"},{"op":"add","path":"/n","value":null}]`,
+			contentType: JSONPatchType,
+			want:        `{"foo":["bar","qux","baz","end"],"n":null}`,
+		},
+		{
+			name:        "JSON patch removes and replaces",
+			doc:         `{"baz":"qux","foo":["bar","baz"]}`,
+			patch:       `[{"op":"remove","path":"/foo/0"},{"op":"replace","path":"/baz","value":"boo"}]`,
+			contentType: JSONPatchType,
+			want:        `{"baz":"boo","foo":["baz"]}`,
+		},
+		{
+			name:        "JSON patch moves and copies",
+			doc:         `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch:       `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"},{"op":"copy","from":"/foo","path":"/copy"}]`,
+			contentType: JSONPatchType,
+			want:        `{"copy":{"bar":"baz"},"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			name:        "JSON patch escapes pointers",
+			doc:         `{"a/b":1,"m~n":2}`,
+			patch:       `[{"op":"remove","path":"/a~1b"},{"op":"test","path":"/m~0n","value":2.0}]`,
+			contentType: JSONPatchType,
+			want:        `{"m~n":2}`,
+		},
+		{
+			name:        "JSON patch fails the test",
+			doc:         `{"baz":"qux"}`,
+			patch:       `[{"op":"test","path":"/baz","value":"bar"}]`,
+			contentType: JSONPatchType,
+			err:         ErrPatchConflict,
+		},
+		{
+			name:        "JSON patch fails on missing targets",
+			doc:         `{"baz":"qux"}`,
+			patch:       `[{"op":"add","path":"/a/b","value":1}]`,
+			contentType: JSONPatchType,
+			err:         ErrPatchConflict,
+		},
+		{
+			name:        "JSON patch rejects removing - from an empty array",
+			doc:         `{"a":[]}`,
+			patch:       `[{"op":"remove","path":"/a/-"}]`,
+			contentType: JSONPatchType,
+			err:         ErrPatchConflict,
+		},
+		{
+			name:        "JSON patch rejects replacing - in an empty array",
+			doc:         `{"a":[]}`,
+			patch:       `[{"op":"replace","path":"/a/-","value":1}]`,
+			contentType: JSONPatchType,
+			err:         ErrPatchConflict,
+		},
+		{
+			name:        "JSON patch rejects testing - in an empty array",
+			doc:         `{"a":[]}`,
+			patch:       `[{"op":"test","path":"/a/-","value":1}]`,
+			contentType: JSONPatchType,
+			err:         ErrPatchConflict,
+		},
+		{
+			name:        "JSON patch rejects removing - from an array",
+			doc:         `{"a":[1,2]}`,
+			patch:       `[{"op":"remove","path":"/a/-"}]`,
+			contentType: JSONPatchType,
+			err:         ErrPatchConflict,
+		},
+		{
+			name:        "JSON patch rejects replacing - in an array",
+			doc:         `{"a":[1,2]}`,
+			patch:       `[{"op":"replace","path":"/a/-","value":3}]`,
+			contentType: JSONPatchType,
+			err:         ErrPatchConflict,
+		},
+		{
+			name:        "JSON patch rejects adding below -",
+			doc:         `{"a":[{}]}`,
+			patch:       `[{"op":"add","path":"/a/-/b","value":1}]`,
+			contentType: JSONPatchType,
+			err:         ErrPatchConflict,
+		},
+		{
+			name:        "JSON patch rejects unknown operations",
+			doc:         `{}`,
+			patch:       `[{"op":"invert","path":"/a"}]`,
+			contentType: JSONPatchType,
+			err:         ErrInvalidPatch,
+		},
+		{
+			name:        "JSON patch rejects missing values",
+			doc:         `{}`,
+			patch:       `[{"op":"add","path":"/a"}]`,
+			contentType: JSONPatchType,
+			err:         ErrInvalidPatch,
+		},
+		{
+			name:        "JSON patch is an array",
+			doc:         `{}`,
+			patch:       `{"op":"add","path":"/a","value":1}`,
+			contentType: JSONPatchType,
+			err:         ErrInvalidPatch,
+		},
+		{
+			name:        "rejects invalid JSON",
+			doc:         `{}`,
+			patch:       `{`,
+			contentType: MergePatchType,
+			err:         ErrInvalidPatch,
+		},
+		{
+			name:        "rejects other media types",
+			doc:         `{}`,
+			patch:       `a=b`,
+			contentType: "application/x-www-form-urlencoded",
+			err:         ErrUnsupportedPatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			have, err := applyPatch([]byte(tc.doc), []byte(tc.patch), tc.contentType)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, found %v", tc.err, err)
+			}
+			if string(have) != tc.want {
+				t.Errorf("expected document %s, found %s", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestIsJSON(t *testing.T) {
+	for contentType, want := range map[string]bool{
+		"application/json":                true,
+		"application/json; charset=utf-8": true,
+		"application/vnd.api+json":        true,
+		"text/plain":                      false,
+		"":                                false,
+	} {
+		if have := isJSON(contentType); have != want {
+			t.Errorf("expected %v for %q, found %v", want, contentType, have)
+		}
+	}
+}
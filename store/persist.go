@@ -0,0 +1,211 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Record is the serialisable form of an entry, as saved in the persistence
+// file. The Body is encoded in base64 in JSON. The Query, Latency and
+// Bandwidth hold the values of the corresponding apimock headers. The Steps
+// are the responses following this one in a sequence; their Method and Path
+// are ignored.
+type Record struct {
+	Method      string      `json:"method"`
+	Path        string      `json:"path"`
+	ContentType string      `json:"contentType"`
+	Body        []byte      `json:"body"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Query       string      `json:"query,omitempty"`
+	ModTime     time.Time   `json:"modTime"`
+	Latency     string      `json:"latency,omitempty"`
+	Bandwidth   string      `json:"bandwidth,omitempty"`
+	Steps       []Record    `json:"steps,omitempty"`
+	Cycle       bool        `json:"cycle,omitempty"`
+}
+
+func (e entry) record(k key) Record {
+	var steps []Record
+	for _, step := range e.next {
+		steps = append(steps, step.record(key{}))
+	}
+
+	return Record{
+		Method:      k.method,
+		Path:        k.path,
+		ContentType: e.contentType,
+		Body:        e.body,
+		Status:      e.status,
+		Header:      e.header,
+		Query:       e.query.String(),
+		ModTime:     e.modTime,
+		Latency:     e.latency.String(),
+		Bandwidth:   e.bandwidth.String(),
+		Steps:       steps,
+		Cycle:       e.cycle,
+	}
+}
+
+func (r Record) entry() entry {
+	// Invalid values fall back to the ones of the store
+	query, _ := ParseQueryMatching(r.Query)
+	latency, _ := ParseLatency(r.Latency)
+	bandwidth, _ := ParseBandwidth(r.Bandwidth)
+
+	e := entry{
+		contentType: r.ContentType,
+		body:        r.Body,
+		status:      r.Status,
+		header:      r.Header,
+		query:       query,
+		modTime:     r.ModTime,
+		latency:     latency,
+		bandwidth:   bandwidth,
+	}
+
+	// The call counters are not persisted: sequences start over
+	for _, step := range r.Steps {
+		e = e.appendStep(step.entry(), r.Cycle)
+	}
+
+	return e
+}
+
+// ErrInvalidRecord is returned when loading a record with invalid values.
+var ErrInvalidRecord = errors.New("invalid record")
+
+// validate reports the first invalid value of the record.
+func (r Record) validate() error {
+	if r.Method == "" || !strings.HasPrefix(r.Path, "/") {
+		return fmt.Errorf("%w: invalid key %q %q", ErrInvalidRecord, r.Method, r.Path)
+	}
+	return r.validateResponse()
+}
+
+func (r Record) validateResponse() error {
+	if r.Status != 0 && (r.Status < 100 || r.Status > 599) {
+		return fmt.Errorf("%w %s %s: invalid status %d", ErrInvalidRecord, r.Method, r.Path, r.Status)
+	}
+	if _, err := ParseQueryMatching(r.Query); err != nil {
+		return fmt.Errorf("%w %s %s: %v", ErrInvalidRecord, r.Method, r.Path, err)
+	}
+	if _, err := ParseLatency(r.Latency); err != nil {
+		return fmt.Errorf("%w %s %s: %v", ErrInvalidRecord, r.Method, r.Path, err)
+	}
+	if _, err := ParseBandwidth(r.Bandwidth); err != nil {
+		return fmt.Errorf("%w %s %s: %v", ErrInvalidRecord, r.Method, r.Path, err)
+	}
+	for _, step := range r.Steps {
+		step.Method, step.Path = r.Method, r.Path
+		if err := step.validateResponse(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r Record) key() key {
+	return key{r.Method, r.Path}
+}
+
+// snapshot is the content of the persistence file.
+type snapshot struct {
+	Entries   []Record       `json:"entries"`
+	Sequences map[string]int `json:"sequences,omitempty"`
+}
+
+// save writes the store content to the persistence file, if one is
+// configured. The caller must hold the lock.
+func (s *Store) save() error {
+	if s.persistencePath == "" {
+		return nil
+	}
+
+	snap := snapshot{
+		Entries:   make([]Record, 0, len(s.entries)),
+		Sequences: s.sequences,
+	}
+	for k, e := range s.entries {
+		snap.Entries = append(snap.Entries, e.record(k))
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.persistencePath, data)
+}
+
+// Restore replaces the store content with the one saved in the persistence
+// file. A missing persistence file is not an error: the store is left empty,
+// and the file will be created on the first change.
+// Restore has no effect if the store was not initialised WithPersistence.
+func (s *Store) Restore() error {
+	if s.persistencePath == "" {
+		return nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	data, err := ioutil.ReadFile(s.persistencePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+
+	s.entries = make(map[key]entry, len(snap.Entries))
+	for _, r := range snap.Entries {
+		s.entries[r.key()] = r.entry()
+	}
+
+	s.sequences = make(map[string]int, len(snap.Sequences))
+	for collection, id := range snap.Sequences {
+		s.sequences[collection] = id
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory, then
+// renames it over the target path, so that the target is never left
+// half-written.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apimock")
+	if err != nil {
+		t.Fatalf("creating the temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "store.json")
+
+	t.Run("missing file restores an empty store", func(t *testing.T) {
+		s := New(WithPersistence(path))
+		if err := s.Restore(); err != nil {
+			t.Fatalf("restoring: %v", err)
+		}
+		if have := len(s.entries); have != 0 {
+			t.Errorf("expected no entries, found %d", have)
+		}
+	})
+
+	t.Run("changes survive a restart", func(t *testing.T) {
+		s := New(WithPersistence(path))
+
+		req, _ := http.NewRequest("PUT", "/one", strings.NewReader("first"))
+		req.Header.Set("Content-Type", "text/first")
+		if err := s.Set(http.MethodGet, "/one", req); err != nil {
+			t.Fatalf("setting: %v", err)
+		}
+
+		req, _ = http.NewRequest("PUT", "/two", strings.NewReader("second"))
+		if err := s.Set(http.MethodPost, "/two", req); err != nil {
+			t.Fatalf("setting: %v", err)
+		}
+
+		req, _ = http.NewRequest("POST", "/items", strings.NewReader("item"))
+		if _, err := s.Add("/items", req); err != nil {
+			t.Fatalf("adding: %v", err)
+		}
+
+		req, _ = http.NewRequest("PUT", "/three", strings.NewReader("third"))
+		if err := s.Set(http.MethodGet, "/three", req); err != nil {
+			t.Fatalf("setting: %v", err)
+		}
+
+		if _, err := s.Del(http.MethodGet, "/three", nil); err != nil {
+			t.Fatalf("deleting: %v", err)
+		}
+
+		restarted := New(WithPersistence(path))
+		if err := restarted.Restore(); err != nil {
+			t.Fatalf("restoring: %v", err)
+		}
+
+		e, ok := restarted.entries[key{http.MethodGet, "/one"}]
+		if !ok {
+			t.Fatalf("expected entry %q", "/one")
+		}
+		if want, have := "first", string(e.body); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+		if want, have := "text/first", e.contentType; want != have {
+			t.Errorf("expected content type %q, found %q", want, have)
+		}
+		if _, ok := restarted.entries[key{http.MethodPost, "/two"}]; !ok {
+			t.Errorf("expected entry %q", "POST /two")
+		}
+		if _, ok := restarted.entries[key{http.MethodGet, "/three"}]; ok {
+			t.Errorf("unexpected entry %q", "/three")
+		}
+		if want, have := 1, restarted.sequences["/items"]; want != have {
+			t.Errorf("expected sequence %d, found %d", want, have)
+		}
+	})
+
+	t.Run("leaves no temporary file behind", func(t *testing.T) {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("reading the directory: %v", err)
+		}
+		if have := len(files); have != 1 {
+			t.Errorf("expected 1 file, found %d", have)
+		}
+	})
+
+	t.Run("corrupted file is an error", func(t *testing.T) {
+		if err := ioutil.WriteFile(path, []byte("{not json"), 0644); err != nil {
+			t.Fatalf("writing the file: %v", err)
+		}
+		s := New(WithPersistence(path))
+		if err := s.Restore(); err == nil {
+			t.Error("expected an error, found nil")
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type queryMode int
+
+const (
+	queryUnset queryMode = iota
+	queryExact
+	queryUnordered
+	queryParams
+	queryIgnore
+)
+
+// QueryMatching is the policy for matching the query string of a request
+// against the query string of a saved key. Its zero value defers to the
+// policy of the store, which is "exact" unless configured otherwise.
+type QueryMatching struct {
+	mode queryMode
+
+	// params lists the parameters required by the "params" mode.
+	params []string
+}
+
+// ParseQueryMatching parses a query matching policy. Accepted values are:
+//   - "exact": the query strings are identical
+//   - "unordered": the query strings have the same parameters and values, in any order
+//   - "ignore": the query strings are not compared
+//   - "params=a,b": the request has the parameters a and b, with the values of the saved key if it has them
+//
+// The empty string is the zero QueryMatching.
+func ParseQueryMatching(s string) (QueryMatching, error) {
+	switch s {
+	case "":
+		return QueryMatching{}, nil
+	case "exact":
+		return QueryMatching{mode: queryExact}, nil
+	case "unordered":
+		return QueryMatching{mode: queryUnordered}, nil
+	case "ignore":
+		return QueryMatching{mode: queryIgnore}, nil
+	}
+
+	if strings.HasPrefix(s, "params=") {
+		var params []string
+		for _, param := range strings.Split(strings.TrimPrefix(s, "params="), ",") {
+			if param = strings.TrimSpace(param); param != "" {
+				params = append(params, param)
+			}
+		}
+		if len(params) > 0 {
+			return QueryMatching{mode: queryParams, params: params}, nil
+		}
+	}
+
+	return QueryMatching{}, fmt.Errorf("unknown query matching %q", s)
+}
+
+func (m QueryMatching) String() string {
+	switch m.mode {
+	case queryExact:
+		return "exact"
+	case queryUnordered:
+		return "unordered"
+	case queryIgnore:
+		return "ignore"
+	case queryParams:
+		return "params=" + strings.Join(m.params, ",")
+	default:
+		return ""
+	}
+}
+
+// or returns m, or def if m is the zero QueryMatching.
+func (m QueryMatching) or(def QueryMatching) QueryMatching {
+	if m.mode == queryUnset {
+		return def
+	}
+	return m
+}
+
+// match reports whether the requested query string matches the saved one.
+func (m QueryMatching) match(saved, requested string) bool {
+	switch m.mode {
+	case queryIgnore:
+		return true
+	case queryUnordered:
+		return equalQueries(saved, requested)
+	case queryParams:
+		return hasParams(saved, requested, m.params)
+	default:
+		return saved == requested
+	}
+}
+
+// equalQueries reports whether the query strings hold the same parameters
+// with the same values, regardless of their order.
+func equalQueries(a, b string) bool {
+	x, err := url.ParseQuery(a)
+	if err != nil {
+		return a == b
+	}
+	y, err := url.ParseQuery(b)
+	if err != nil {
+		return a == b
+	}
+
+	if len(x) != len(y) {
+		return false
+	}
+
+	for param, values := range x {
+		other, ok := y[param]
+		if !ok || len(values) != len(other) {
+			return false
+		}
+
+		values = append([]string(nil), values...)
+		other = append([]string(nil), other...)
+		sort.Strings(values)
+		sort.Strings(other)
+		for i := range values {
+			if values[i] != other[i] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// hasParams reports whether the requested query string holds every
+// parameter, with the same value as the saved query string when it has one.
+func hasParams(saved, requested string, params []string) bool {
+	x, err := url.ParseQuery(saved)
+	if err != nil {
+		return false
+	}
+	y, err := url.ParseQuery(requested)
+	if err != nil {
+		return false
+	}
+
+	for _, param := range params {
+		if _, ok := y[param]; !ok {
+			return false
+		}
+		if want, ok := x[param]; ok && y.Get(param) != want[0] {
+			return false
+		}
+	}
+
+	return true
+}
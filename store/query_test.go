@@ -0,0 +1,70 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseQueryMatching(t *testing.T) {
+	testCases := [...]struct {
+		value string
+		want  string
+		ok    bool
+	}{
+		{"", "", true},
+		{"exact", "exact", true},
+		{"unordered", "unordered", true},
+		{"ignore", "ignore", true},
+		{"params=q,page", "params=q,page", true},
+		{"params= q , ,page", "params=q,page", true},
+		{"params=", "", false},
+		{"fuzzy", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			m, err := ParseQueryMatching(tc.value)
+			if ok := err == nil; ok != tc.ok {
+				t.Fatalf("expected ok %v, found error %v", tc.ok, err)
+			}
+			if have := m.String(); have != tc.want {
+				t.Errorf("expected %q, found %q", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestQueryMatchingMatch(t *testing.T) {
+	testCases := [...]struct {
+		policy    string
+		saved     string
+		requested string
+		want      bool
+	}{
+		{"exact", "q=a&page=1", "q=a&page=1", true},
+		{"exact", "q=a&page=1", "page=1&q=a", false},
+		{"unordered", "q=a&page=1", "page=1&q=a", true},
+		{"unordered", "q=a&q=b", "q=b&q=a", true},
+		{"unordered", "q=a&page=1", "q=a", false},
+		{"unordered", "q=a", "q=b", false},
+		{"ignore", "", "q=a", true},
+		{"ignore", "q=b", "q=a", true},
+		{"params=q", "", "q=a&page=1", true},
+		{"params=q", "", "page=1", false},
+		{"params=q", "q=a", "q=a&page=1", true},
+		{"params=q", "q=a", "q=b&page=1", false},
+		{"params=q,page", "", "q=a", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s %q %q", tc.policy, tc.saved, tc.requested), func(t *testing.T) {
+			m, err := ParseQueryMatching(tc.policy)
+			if err != nil {
+				t.Fatalf("parsing the policy: %v", err)
+			}
+			if have := m.match(tc.saved, tc.requested); have != tc.want {
+				t.Errorf("expected %v, found %v", tc.want, have)
+			}
+		})
+	}
+}
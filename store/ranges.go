@@ -0,0 +1,216 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errUnsatisfiableRange is returned when none of the requested ranges
+// overlaps the body.
+var errUnsatisfiableRange = errors.New("unsatisfiable range")
+
+// maxRanges is the maximum number of ranges of a Range header. Requests
+// asking for more are answered with the whole body.
+const maxRanges = 100
+
+// byteRange is a range of bytes of the body, with inclusive bounds.
+type byteRange struct {
+	start, end int
+}
+
+func (r byteRange) contentRange(size int) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.end, size)
+}
+
+// parseRange parses the value of a Range header, as defined in RFC 7233,
+// section 2.1, for a body of the given size. The ranges that start past the
+// end of the body are dropped.
+// An error is returned if the header is malformed, or wrapping
+// errUnsatisfiableRange if no range is left.
+func parseRange(value string, size int) ([]byteRange, error) {
+	if !strings.HasPrefix(value, "bytes=") {
+		return nil, fmt.Errorf("invalid range %q", value)
+	}
+
+	var ranges []byteRange
+	for _, spec := range strings.Split(strings.TrimPrefix(value, "bytes="), ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		i := strings.IndexByte(spec, '-')
+		if i < 0 {
+			return nil, fmt.Errorf("invalid range %q", value)
+		}
+		first, last := spec[:i], spec[i+1:]
+
+		var r byteRange
+		if first == "" {
+			// Suffix range: the last n bytes
+			n, err := strconv.Atoi(last)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid range %q", value)
+			}
+			if n == 0 || size == 0 {
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			r = byteRange{size - n, size - 1}
+		} else {
+			start, err := strconv.Atoi(first)
+			if err != nil || start < 0 {
+				return nil, fmt.Errorf("invalid range %q", value)
+			}
+			end := size - 1
+			if last != "" {
+				if end, err = strconv.Atoi(last); err != nil || end < start {
+					return nil, fmt.Errorf("invalid range %q", value)
+				}
+				if end > size-1 {
+					end = size - 1
+				}
+			}
+			if start >= size {
+				continue
+			}
+			r = byteRange{start, end}
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	if len(ranges) == 0 {
+		return nil, errUnsatisfiableRange
+	}
+
+	return ranges, nil
+}
+
+// rangeApplies reports whether the Range header of the request is to be
+// honoured, according to its If-Range header.
+func (e entry) rangeApplies(req *http.Request) bool {
+	value := req.Header.Get("If-Range")
+	if value == "" {
+		return true
+	}
+
+	if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "W/") {
+		return !strings.HasPrefix(value, "W/") && value == e.etag()
+	}
+
+	t, err := http.ParseTime(value)
+	return err == nil && t.Equal(e.lastModified().Truncate(time.Second))
+}
+
+// serveRanges sends the requested ranges of the body with status 206
+// Partial Content: as is for a single range, as a multipart/byteranges
+// document for several ones. Unsatisfiable ranges are answered with status
+// 416 Range Not Satisfiable.
+// The returned boolean is false if the Range header is malformed, and must
+// be ignored.
+func (e entry) serveRanges(rw http.ResponseWriter, value string) bool {
+	size := len(e.body)
+
+	ranges, err := parseRange(value, size)
+	if errors.Is(err, errUnsatisfiableRange) {
+		rw.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		http.Error(rw, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return true
+	}
+	if err != nil {
+		return false
+	}
+
+	// Like net/http's ServeContent, ignore the Range header if the ranges
+	// add up to more than the body: the client asks for overlapping ranges,
+	// sending the whole body is cheaper
+	if len(ranges) > maxRanges || sumRanges(ranges) > size {
+		return false
+	}
+
+	if len(ranges) == 1 {
+		r := ranges[0]
+		rw.Header().Set("Content-Type", e.contentType)
+		rw.Header().Set("Content-Range", r.contentRange(size))
+		rw.Header().Set("Content-Length", strconv.Itoa(r.end-r.start+1))
+		rw.WriteHeader(http.StatusPartialContent)
+		rw.Write(e.body[r.start : r.end+1])
+		return true
+	}
+
+	mw := multipart.NewWriter(rw)
+	length, err := e.multipartSize(ranges, mw.Boundary())
+	if err != nil {
+		return false
+	}
+
+	rw.Header().Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+	rw.Header().Set("Content-Length", strconv.Itoa(length))
+	rw.WriteHeader(http.StatusPartialContent)
+	e.writeParts(mw, ranges, true)
+	return true
+}
+
+func sumRanges(ranges []byteRange) int {
+	var sum int
+	for _, r := range ranges {
+		sum += r.end - r.start + 1
+	}
+	return sum
+}
+
+// partHeader returns the header of the multipart/byteranges part holding r.
+func (e entry) partHeader(r byteRange) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Type":  {e.contentType},
+		"Content-Range": {r.contentRange(len(e.body))},
+	}
+}
+
+// writeParts writes the multipart/byteranges document of the ranges to mw.
+// The bodies of the parts are only written if withBody is true.
+func (e entry) writeParts(mw *multipart.Writer, ranges []byteRange, withBody bool) error {
+	for _, r := range ranges {
+		part, err := mw.CreatePart(e.partHeader(r))
+		if err != nil {
+			return err
+		}
+		if withBody {
+			if _, err := part.Write(e.body[r.start : r.end+1]); err != nil {
+				return err
+			}
+		}
+	}
+	return mw.Close()
+}
+
+// multipartSize returns the length of the multipart/byteranges document of
+// the ranges, without building it.
+func (e entry) multipartSize(ranges []byteRange, boundary string) (int, error) {
+	var w countingWriter
+	mw := multipart.NewWriter(&w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return 0, err
+	}
+	if err := e.writeParts(mw, ranges, false); err != nil {
+		return 0, err
+	}
+	return int(w) + sumRanges(ranges), nil
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter int
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	*w += countingWriter(len(b))
+	return len(b), nil
+}
@@ -0,0 +1,199 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRange(t *testing.T) {
+	testCases := [...]struct {
+		value string
+		want  []byteRange
+		err   bool
+	}{
+		{"bytes=0-4", []byteRange{{0, 4}}, false},
+		{"bytes=5-", []byteRange{{5, 9}}, false},
+		{"bytes=-3", []byteRange{{7, 9}}, false},
+		{"bytes=-30", []byteRange{{0, 9}}, false},
+		{"bytes=8-20", []byteRange{{8, 9}}, false},
+		{"bytes=0-1, 4-5", []byteRange{{0, 1}, {4, 5}}, false},
+		{"bytes=0-1,20-30", []byteRange{{0, 1}}, false},
+		{"bytes=20-30", nil, true},
+		{"bytes=5-4", nil, true},
+		{"bytes=a-b", nil, true},
+		{"items=0-4", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			have, err := parseRange(tc.value, 10)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %v, found %v", tc.err, err)
+			}
+			if fmt.Sprint(have) != fmt.Sprint(tc.want) {
+				t.Errorf("expected ranges %v, found %v", tc.want, have)
+			}
+		})
+	}
+
+	t.Run("starting past the end is unsatisfiable", func(t *testing.T) {
+		if _, err := parseRange("bytes=10-", 10); !errors.Is(err, errUnsatisfiableRange) {
+			t.Errorf("expected error %v, found %v", errUnsatisfiableRange, err)
+		}
+	})
+}
+
+func TestEntryRanges(t *testing.T) {
+	modTime := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	e := entry{contentType: "text/plain", body: []byte("0123456789"), modTime: modTime}
+
+	serve := func(header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/video", nil)
+		for name, value := range header {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("advertises range support", func(t *testing.T) {
+		if want, have := "bytes", serve(nil).Header().Get("Accept-Ranges"); want != have {
+			t.Errorf("expected Accept-Ranges %q, found %q", want, have)
+		}
+	})
+
+	t.Run("sends a single range", func(t *testing.T) {
+		rec := serve(map[string]string{"Range": "bytes=2-5"})
+		if want, have := 206, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+		if want, have := "bytes 2-5/10", rec.Header().Get("Content-Range"); want != have {
+			t.Errorf("expected Content-Range %q, found %q", want, have)
+		}
+		if want, have := "4", rec.Header().Get("Content-Length"); want != have {
+			t.Errorf("expected Content-Length %q, found %q", want, have)
+		}
+		if want, have := "2345", rec.Body.String(); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+	})
+
+	t.Run("sends multiple ranges", func(t *testing.T) {
+		rec := serve(map[string]string{"Range": "bytes=0-1,-2"})
+		if want, have := 206, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+
+		mediaType, params, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+		if err != nil || mediaType != "multipart/byteranges" {
+			t.Fatalf("expected a multipart/byteranges content type, found %q", rec.Header().Get("Content-Type"))
+		}
+
+		if want, have := strconv.Itoa(rec.Body.Len()), rec.Header().Get("Content-Length"); want != have {
+			t.Errorf("expected Content-Length %q, found %q", want, have)
+		}
+
+		mr := multipart.NewReader(rec.Body, params["boundary"])
+		for _, want := range []struct{ contentRange, body string }{
+			{"bytes 0-1/10", "01"},
+			{"bytes 8-9/10", "89"},
+		} {
+			part, err := mr.NextPart()
+			if err != nil {
+				t.Fatalf("reading the part: %v", err)
+			}
+			if have := part.Header.Get("Content-Range"); have != want.contentRange {
+				t.Errorf("expected Content-Range %q, found %q", want.contentRange, have)
+			}
+			if have := part.Header.Get("Content-Type"); have != "text/plain" {
+				t.Errorf("expected Content-Type %q, found %q", "text/plain", have)
+			}
+			body, _ := ioutil.ReadAll(part)
+			if have := string(body); have != want.body {
+				t.Errorf("expected part body %q, found %q", want.body, have)
+			}
+		}
+	})
+
+	t.Run("sends the whole body for overlapping ranges", func(t *testing.T) {
+		rec := serve(map[string]string{"Range": "bytes=0-,0-"})
+		if want, have := 200, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+		if want, have := "0123456789", rec.Body.String(); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+	})
+
+	t.Run("sends the whole body for too many ranges", func(t *testing.T) {
+		e := entry{body: bytes.Repeat([]byte("0"), 1000)}
+		specs := make([]string, maxRanges+1)
+		for i := range specs {
+			specs[i] = fmt.Sprintf("%d-%d", i*2, i*2)
+		}
+		req := httptest.NewRequest("GET", "/video", nil)
+		req.Header.Set("Range", "bytes="+strings.Join(specs, ","))
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if want, have := 200, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+	})
+
+	t.Run("rejects unsatisfiable ranges", func(t *testing.T) {
+		rec := serve(map[string]string{"Range": "bytes=20-"})
+		if want, have := 416, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+		if want, have := "bytes */10", rec.Header().Get("Content-Range"); want != have {
+			t.Errorf("expected Content-Range %q, found %q", want, have)
+		}
+	})
+
+	t.Run("ignores malformed ranges", func(t *testing.T) {
+		rec := serve(map[string]string{"Range": "bytes=x-y"})
+		if want, have := 200, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+	})
+
+	t.Run("honours a matching If-Range", func(t *testing.T) {
+		for _, ifRange := range []string{e.etag(), modTime.Format(http.TimeFormat)} {
+			rec := serve(map[string]string{"Range": "bytes=0-1", "If-Range": ifRange})
+			if want, have := 206, rec.Code; want != have {
+				t.Errorf("expected status %d with If-Range %q, found %d", want, ifRange, have)
+			}
+		}
+	})
+
+	t.Run("sends the whole body on a stale If-Range", func(t *testing.T) {
+		for _, ifRange := range []string{`"stale"`, modTime.Add(time.Hour).Format(http.TimeFormat)} {
+			rec := serve(map[string]string{"Range": "bytes=0-1", "If-Range": ifRange})
+			if want, have := 200, rec.Code; want != have {
+				t.Errorf("expected status %d with If-Range %q, found %d", want, ifRange, have)
+			}
+		}
+	})
+
+	t.Run("ignores ranges on custom status codes", func(t *testing.T) {
+		e := entry{body: []byte("0123456789"), status: 500}
+		req := httptest.NewRequest("GET", "/video", nil)
+		req.Header.Set("Range", "bytes=0-1")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if want, have := 500, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// sequenceMode tells how a saved response relates to the one already saved
+// for the same key.
+type sequenceMode int
+
+const (
+	// sequenceUnset replaces the saved response.
+	sequenceUnset sequenceMode = iota
+
+	// sequenceLast appends the response to the sequence; the last response
+	// of the sequence is repeated once all of them have been served.
+	sequenceLast
+
+	// sequenceCycle appends the response to the sequence; the sequence
+	// starts over once all of its responses have been served.
+	sequenceCycle
+)
+
+// sequenceFromRequest parses the sequence mode requested for the saved
+// response.
+func sequenceFromRequest(req *http.Request) (sequenceMode, error) {
+	switch value := req.Header.Get(SequenceHeader); value {
+	case "":
+		return sequenceUnset, nil
+	case "last":
+		return sequenceLast, nil
+	case "cycle":
+		return sequenceCycle, nil
+	default:
+		return sequenceUnset, invalidHeader(SequenceHeader, value)
+	}
+}
+
+// appendStep returns a copy of e with step appended to its sequence.
+func (e entry) appendStep(step entry, cycle bool) entry {
+	e.next = append(append([]entry(nil), e.next...), step)
+	e.cycle = cycle
+	if e.calls == nil {
+		e.calls = new(uint64)
+	}
+	return e
+}
+
+// step returns the response of the sequence that answers the next call. If
+// advance is true, the call is counted.
+func (e entry) step(advance bool) entry {
+	if len(e.next) == 0 {
+		return e
+	}
+
+	var n uint64
+	if advance {
+		n = atomic.AddUint64(e.calls, 1) - 1
+	} else {
+		n = atomic.LoadUint64(e.calls)
+	}
+
+	length := uint64(len(e.next)) + 1
+	switch {
+	case e.cycle:
+		n %= length
+	case n >= length:
+		n = length - 1
+	}
+
+	if n == 0 {
+		return e
+	}
+	return e.next[n-1]
+}
+
+// rewind makes the sequence start over.
+func (e entry) rewind() {
+	if e.calls != nil {
+		atomic.StoreUint64(e.calls, 0)
+	}
+}
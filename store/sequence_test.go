@@ -0,0 +1,130 @@
+package store
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setSequence saves the bodies as a sequence of responses to GET path.
+func setSequence(t *testing.T, s *Store, path, mode string, bodies ...string) {
+	t.Helper()
+	for i, body := range bodies {
+		req, _ := http.NewRequest("PUT", path, strings.NewReader(body))
+		if i > 0 {
+			req.Header.Set(SequenceHeader, mode)
+		}
+		if err := s.Set(http.MethodGet, path, req); err != nil {
+			t.Fatalf("setting: %v", err)
+		}
+	}
+}
+
+// bodies returns the bodies of n successive GET responses.
+func bodies(s *Store, path string, n int) string {
+	var have []string
+	for i := 0; i < n; i++ {
+		h, _ := s.Get(http.MethodGet, path)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		have = append(have, rec.Body.String())
+	}
+	return strings.Join(have, ",")
+}
+
+func TestSequence(t *testing.T) {
+	testCases := [...]struct {
+		mode string
+		want string
+	}{
+		{"last", "pending,running,done,done,done"},
+		{"cycle", "pending,running,done,pending,running"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.mode, func(t *testing.T) {
+			s := New()
+			setSequence(t, s, "/job", tc.mode, "pending", "running", "done")
+			if have := bodies(s, "/job", 5); have != tc.want {
+				t.Errorf("expected %q, found %q", tc.want, have)
+			}
+		})
+	}
+
+	t.Run("replaces the sequence without the header", func(t *testing.T) {
+		s := New()
+		setSequence(t, s, "/job", "last", "pending", "done")
+		setSequence(t, s, "/job", "", "again")
+		if want, have := "again,again", bodies(s, "/job", 2); want != have {
+			t.Errorf("expected %q, found %q", want, have)
+		}
+	})
+
+	t.Run("peeking does not count", func(t *testing.T) {
+		s := New()
+		setSequence(t, s, "/job", "last", "pending", "done")
+		for i := 0; i < 3; i++ {
+			s.Peek(http.MethodGet, "/job")
+		}
+		if want, have := "pending,done", bodies(s, "/job", 2); want != have {
+			t.Errorf("expected %q, found %q", want, have)
+		}
+	})
+
+	t.Run("rewinds", func(t *testing.T) {
+		s := New()
+		setSequence(t, s, "/job", "last", "pending", "done")
+		bodies(s, "/job", 2)
+		if !s.Rewind(http.MethodGet, "/job") {
+			t.Fatal("expected Rewind to find the entry")
+		}
+		if want, have := "pending,done", bodies(s, "/job", 2); want != have {
+			t.Errorf("expected %q, found %q", want, have)
+		}
+		if s.Rewind(http.MethodGet, "/unknown") {
+			t.Error("expected Rewind not to find an unknown entry")
+		}
+	})
+
+	t.Run("expands the template parameters in every step", func(t *testing.T) {
+		s := New()
+		setSequence(t, s, "/jobs/%7Bid%7D", "last", "{{id}} pending", "{{id}} done")
+		if want, have := "42 pending,42 done", bodies(s, "/jobs/42", 2); want != have {
+			t.Errorf("expected %q, found %q", want, have)
+		}
+	})
+
+	t.Run("rejects invalid modes", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/job", strings.NewReader(""))
+		req.Header.Set(SequenceHeader, "forever")
+		if err := New().Set(http.MethodGet, "/job", req); !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("expected ErrInvalidHeader, found %v", err)
+		}
+	})
+}
+
+func TestSequencePersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apimock")
+	if err != nil {
+		t.Fatalf("creating the temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "store.json")
+
+	s := New(WithPersistence(path))
+	setSequence(t, s, "/job", "cycle", "pending", "done")
+
+	restarted := New(WithPersistence(path))
+	if err := restarted.Restore(); err != nil {
+		t.Fatalf("restoring: %v", err)
+	}
+	if want, have := "pending,done,pending", bodies(restarted, "/job", 3); want != have {
+		t.Errorf("expected %q, found %q", want, have)
+	}
+}
@@ -3,63 +3,426 @@ package store
 import (
 	"io/ioutil"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
-// Store saves an HTTP request data associated to a string key.
+// key identifies an entry by the HTTP method and the path it answers to.
+type key struct {
+	method string
+	path   string
+}
+
+// Store saves an HTTP request data associated to an HTTP method and a string key.
 // It is safe for concurrent usage.
 // Store is not directly usable; please initialise one with New.
 type Store struct {
 	sync.RWMutex
-	entries map[string]entry
+	entries map[key]entry
+
+	// sequences holds the last ID generated for each collection.
+	sequences map[string]int
 
 	overrideContentType string
 	defaultContentType  string
+
+	// queryMatching is the default policy for matching query strings.
+	queryMatching QueryMatching
+
+	// latency is the default delay applied before sending the responses.
+	latency Latency
+
+	// bandwidth is the default rate at which the response bodies are sent.
+	bandwidth Bandwidth
+
+	// persistencePath is the file where the entries are saved on every
+	// change. Persistence is disabled when it is empty.
+	persistencePath string
 }
 
-// Get returns the HTTP request data.
+// Get returns the HTTP request data saved for the given method.
+// If no data was saved for the exact key string, Get looks for a key
+// matching it, either as a path template (see matchTemplate) or according to
+// the query matching policy, and substitutes the template parameters in the
+// saved body.
+// If a sequence of responses was saved, Get returns the next one.
 // The returned handler will send back the original HTTP request content type and body.
 // The returned boolean is true if a request was found associated to the given key string.
-func (s *Store) Get(path string) (http.Handler, bool) {
+func (s *Store) Get(method, path string) (http.Handler, bool) {
+	return s.get(method, path, true)
+}
+
+// Peek is like Get, but it does not count the call: successive calls to Peek
+// return the same response of a sequence.
+func (s *Store) Peek(method, path string) (http.Handler, bool) {
+	return s.get(method, path, false)
+}
+
+// Echo is like Peek, but the returned handler answers without the latency
+// and the bandwidth: it is meant to echo the response saved by a request back to it.
+func (s *Store) Echo(method, path string) (http.Handler, bool) {
+	e, ok := s.get(method, path, false)
+	return http.HandlerFunc(e.reply), ok
+}
+
+func (s *Store) get(method, path string, advance bool) (entry, bool) {
 	s.RLock()
 	defer s.RUnlock()
 
-	e, ok := s.entries[path]
+	_, e, ok := s.lookup(method, path)
+	e = e.step(advance)
+	e.latency = e.latency.or(s.latency)
+	e.bandwidth = e.bandwidth.or(s.bandwidth)
 	return e, ok
 }
 
-// Set saves a request's data associated to a key string.
-// An error is returned if the request body io.Reader is not readable.
-func (s *Store) Set(path string, req *http.Request) error {
+// lookup returns the entry saved for the method and the key string. If there
+// is none, it looks for the best entry whose path matches, exactly or as a
+// template, and whose query string matches according to its query matching
+// policy. Exact paths are preferred to templates, templates with fewer
+// parameters to the others, and stricter query matching policies to the
+// looser ones. The template parameters are expanded in the returned entry
+// body.
+// The caller must hold the lock.
+func (s *Store) lookup(method, path string) (key, entry, bool) {
+	if e, ok := s.entries[key{method, path}]; ok {
+		return key{method, path}, e, true
+	}
+
+	requestPath, requestQuery := splitKey(path)
+
+	var (
+		found      bool
+		best       key
+		bestEntry  entry
+		bestParams map[string]string
+		bestMode   queryMode
+	)
+	for k, e := range s.entries {
+		if k.method != method {
+			continue
+		}
+
+		savedPath, savedQuery := splitKey(k.path)
+
+		query := e.query.or(s.queryMatching)
+		if !query.match(savedQuery, requestQuery) {
+			continue
+		}
+
+		params, ok := matchTemplate(savedPath, requestPath)
+		if !ok {
+			continue
+		}
+
+		if found {
+			if len(params) != len(bestParams) {
+				if len(params) > len(bestParams) {
+					continue
+				}
+			} else if query.mode != bestMode {
+				if query.mode > bestMode {
+					continue
+				}
+			} else if k.path > best.path {
+				continue
+			}
+		}
+
+		found, best, bestEntry, bestParams, bestMode = true, k, e, params, query.mode
+	}
+
+	if found {
+		bestEntry.body = expand(bestEntry.body, bestParams)
+		if len(bestEntry.next) > 0 {
+			next := make([]entry, len(bestEntry.next))
+			for i, step := range bestEntry.next {
+				step.body = expand(step.body, bestParams)
+				next[i] = step
+			}
+			bestEntry.next = next
+		}
+	}
+
+	return best, bestEntry, found
+}
+
+// Match returns the key of the entry that Get would return for the given
+// method and key string.
+// The returned boolean is true if an entry matched.
+func (s *Store) Match(method, path string) (string, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	k, _, ok := s.lookup(method, path)
+	return k.path, ok
+}
+
+// List returns the GET entries stored one path segment below the given key.
+// The returned handler will send back a JSON array of their bodies, sorted by
+// key.
+// The returned boolean is true if at least one entry was found.
+func (s *Store) List(path string) (http.Handler, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	parent := strings.TrimSuffix(path, "/")
+
+	var keys []string
+	for k := range s.entries {
+		if k.method == http.MethodGet && isChild(parent, k.path) && !isTemplate(k.path) {
+			keys = append(keys, k.path)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, false
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool { return lessKey(keys[i], keys[j]) })
+
+	c := make(collection, len(keys))
+	for i, path := range keys {
+		c[i] = s.entries[key{http.MethodGet, path}]
+	}
+
+	return c, true
+}
+
+// Set saves a request's data associated to a method and a key string.
+// If the request bears the SequenceHeader, the data is appended to the
+// sequence of responses already saved, if any.
+// An error is returned if the request body io.Reader is not readable, if
+// the request bears an invalid apimock header, or wrapping
+// ErrPreconditionFailed if the request conditional headers do not hold.
+func (s *Store) Set(method, path string, req *http.Request) error {
 	s.Lock()
 	defer s.Unlock()
 
-	contentType := contentTypeFromRequest(req, s.overrideContentType, s.defaultContentType)
+	k := key{method, path}
+
+	old, exists := s.entries[k]
+	if err := checkPreconditions(req, old, exists); err != nil {
+		return err
+	}
 
-	body, err := ioutil.ReadAll(req.Body)
+	mode, err := sequenceFromRequest(req)
 	if err != nil {
 		return err
 	}
 
-	s.entries[path] = entry{contentType, body}
+	e, err := entryFromRequest(req, s.overrideContentType, s.defaultContentType)
+	if err != nil {
+		return err
+	}
+
+	if mode != sequenceUnset && exists {
+		e = old.appendStep(e, mode == sequenceCycle)
+	}
 
-	return nil
+	s.entries[k] = e
+
+	return s.save()
 }
 
-// Del deletes the entry associated with the given key.
+// Add saves a request's data as a GET entry under a new key, made of the collection key
+// followed by a generated numeric ID. IDs are sequential for each collection
+// and skip the keys that are already in use.
+// The returned string is the new key.
+// An error is returned if the request body io.Reader is not readable, or if
+// the request bears an invalid apimock header.
+func (s *Store) Add(collection string, req *http.Request) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	e, err := entryFromRequest(req, s.overrideContentType, s.defaultContentType)
+	if err != nil {
+		return "", err
+	}
+
+	collection = strings.TrimSuffix(collection, "/")
+
+	var path string
+	for {
+		s.sequences[collection]++
+		path = collection + "/" + strconv.Itoa(s.sequences[collection])
+		if _, ok := s.entries[key{http.MethodGet, path}]; !ok {
+			break
+		}
+	}
+
+	s.entries[key{http.MethodGet, path}] = e
+
+	return path, s.save()
+}
+
+// Patch applies the patch in the request body to the GET entry associated to
+// the key string. The patch format is chosen according to the request
+// Content-Type: JSON Merge Patch for MergePatchType and "application/json",
+// JSON Patch for JSONPatchType.
+// The returned boolean is true if an entry was associated to the given key.
+// An error is returned if the request body io.Reader is not readable,
+// wrapping ErrPreconditionFailed if the request conditional headers do not
+// hold, or wrapping ErrUnsupportedPatch, ErrInvalidPatch or ErrPatchConflict
+// if the patch could not be applied.
+func (s *Store) Patch(path string, req *http.Request) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	k := key{http.MethodGet, path}
+
+	e, ok := s.entries[k]
+	if !ok {
+		return false, nil
+	}
+
+	if err := checkPreconditions(req, e, true); err != nil {
+		return true, err
+	}
+
+	patch, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return true, err
+	}
+
+	if e, err = e.patch(req, patch); err != nil {
+		return true, err
+	}
+	e.modTime = time.Now()
+
+	s.entries[k] = e
+
+	return true, s.save()
+}
+
+// Del deletes the entry associated with the given method and key.
+// The conditional headers of the request, if not nil, must hold.
 // The returned boolean is true if an entry was actually associated to the given key.
-func (s *Store) Del(path string) bool {
+// An error is returned wrapping ErrPreconditionFailed if the request
+// conditional headers do not hold, or if the change could not be persisted.
+func (s *Store) Del(method, path string, req *http.Request) (bool, error) {
 	s.Lock()
 	defer s.Unlock()
 
-	_, ok := s.entries[path]
+	k := key{method, path}
+
+	e, ok := s.entries[k]
+	if !ok {
+		return false, nil
+	}
+
+	if req != nil {
+		if err := checkPreconditions(req, e, true); err != nil {
+			return true, err
+		}
+	}
+
+	delete(s.entries, k)
+
+	return true, s.save()
+}
+
+// Rewind makes the sequence of responses associated with the given method and
+// key start over.
+// The returned boolean is true if an entry was actually associated to the given key.
+func (s *Store) Rewind(method, path string) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	e, ok := s.entries[key{method, path}]
+	if ok {
+		e.rewind()
+	}
+
+	return ok
+}
+
+// Records returns the serialisable form of every entry, sorted by key and
+// method.
+func (s *Store) Records() []Record {
+	s.RLock()
+	defer s.RUnlock()
+
+	records := make([]Record, 0, len(s.entries))
+	for k, e := range s.entries {
+		records = append(records, e.record(k))
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Path != records[j].Path {
+			return lessKey(records[i].Path, records[j].Path)
+		}
+		return records[i].Method < records[j].Method
+	})
+
+	return records
+}
+
+// Record returns the serialisable form of the entry associated with the
+// given method and key.
+// The returned boolean is true if an entry was actually associated to the given key.
+func (s *Store) Record(method, path string) (Record, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	e, ok := s.entries[key{method, path}]
 	if !ok {
-		return false
+		return Record{}, false
 	}
 
-	delete(s.entries, path)
+	return e.record(key{method, path}), true
+}
+
+// Load saves the records, replacing the entries with the same keys.
+// An error wrapping ErrInvalidRecord is returned, and nothing is saved, if
+// any record is invalid.
+func (s *Store) Load(records []Record) error {
+	for _, r := range records {
+		if err := r.validate(); err != nil {
+			return err
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	for _, r := range records {
+		e := r.entry()
+		if e.modTime.IsZero() {
+			e.modTime = time.Now()
+		}
+		s.entries[r.key()] = e
+	}
 
-	return true
+	return s.save()
+}
+
+// Clear deletes every entry, and makes the collection IDs start over.
+func (s *Store) Clear() error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.entries = make(map[key]entry)
+	s.sequences = make(map[string]int)
+
+	return s.save()
+}
+
+// Reset makes every sequence of responses, and the collection IDs, start
+// over.
+func (s *Store) Reset() error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, e := range s.entries {
+		e.rewind()
+	}
+	s.sequences = make(map[string]int)
+
+	return s.save()
 }
 
 type option func(*Store)
@@ -81,10 +444,45 @@ func WithContentTypeOverride(overrideContentType string) option {
 	}
 }
 
+// WithPersistence is a functional option to modify the behaviour of New.
+// Every change to the store will be saved to the file at the given path.
+// Call Restore to load the saved entries back.
+func WithPersistence(path string) option {
+	return func(s *Store) {
+		s.persistencePath = path
+	}
+}
+
+// WithQueryMatching is a functional option to modify the behaviour of New.
+// The policy will be used for the entries saved without one.
+func WithQueryMatching(m QueryMatching) option {
+	return func(s *Store) {
+		s.queryMatching = m.or(QueryMatching{mode: queryExact})
+	}
+}
+
+// WithLatency is a functional option to modify the behaviour of New.
+// The latency will be applied to the entries saved without one.
+func WithLatency(l Latency) option {
+	return func(s *Store) {
+		s.latency = l
+	}
+}
+
+// WithBandwidth is a functional option to modify the behaviour of New.
+// The bandwidth will be applied to the entries saved without one.
+func WithBandwidth(b Bandwidth) option {
+	return func(s *Store) {
+		s.bandwidth = b
+	}
+}
+
 // New initialises a new Store.
 func New(options ...option) *Store {
 	s := Store{
-		entries: make(map[string]entry),
+		entries:       make(map[key]entry),
+		sequences:     make(map[string]int),
+		queryMatching: QueryMatching{mode: queryExact},
 	}
 
 	for _, apply := range options {
@@ -1,10 +1,13 @@
 package store
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestStoreGet(t *testing.T) {
@@ -29,18 +32,20 @@ func TestStoreGet(t *testing.T) {
 	}
 
 	storeWith := func(path, body string) *Store {
-		return &Store{entries: map[string]entry{path: {body: []byte(body)}}}
+		return &Store{entries: map[key]entry{{http.MethodGet, path}: {body: []byte(body)}}}
 	}
 
 	testCases := [...]struct {
 		name   string
 		store  *Store
+		method string
 		path   string
 		checks []checkFunc
 	}{
 		{
 			"gets existing entry",
 			storeWith("this path", "this entry"),
+			http.MethodGet,
 			"this path",
 			check(
 				hasBody("this entry"),
@@ -50,16 +55,241 @@ func TestStoreGet(t *testing.T) {
 		{
 			"ok is false if not found",
 			storeWith("this path", "this entry"),
+			http.MethodGet,
 			"that path",
 			check(
 				hasOk(false),
 			),
 		},
+		{
+			"ok is false for another method",
+			storeWith("this path", "this entry"),
+			http.MethodPost,
+			"this path",
+			check(
+				hasOk(false),
+			),
+		},
+		{
+			"matches a template",
+			storeWith("/users/{id}", `{"id": "{{id}}"}`),
+			http.MethodGet,
+			"/users/42",
+			check(
+				hasBody(`{"id": "42"}`),
+				hasOk(true),
+			),
+		},
+		{
+			"prefers the exact match to the template",
+			&Store{entries: map[key]entry{
+				{http.MethodGet, "/users/{id}"}: {body: []byte("template")},
+				{http.MethodGet, "/users/me"}:   {body: []byte("exact")},
+			}},
+			http.MethodGet,
+			"/users/me",
+			check(
+				hasBody("exact"),
+			),
+		},
+		{
+			"prefers the template with fewer parameters",
+			&Store{entries: map[key]entry{
+				{http.MethodGet, "/{kind}/{id}"}: {body: []byte("generic")},
+				{http.MethodGet, "/users/{id}"}:  {body: []byte("users")},
+			}},
+			http.MethodGet,
+			"/users/42",
+			check(
+				hasBody("users"),
+			),
+		},
+		{
+			"matches with the entry query policy",
+			&Store{entries: map[key]entry{
+				{http.MethodGet, "/search?q=a&page=1"}: {body: []byte("found"), query: QueryMatching{mode: queryUnordered}},
+			}},
+			http.MethodGet,
+			"/search?page=1&q=a",
+			check(
+				hasBody("found"),
+			),
+		},
+		{
+			"matches with the store query policy",
+			&Store{
+				entries: map[key]entry{
+					{http.MethodGet, "/search"}: {body: []byte("found")},
+				},
+				queryMatching: QueryMatching{mode: queryIgnore},
+			},
+			http.MethodGet,
+			"/search?q=a",
+			check(
+				hasBody("found"),
+			),
+		},
+		{
+			"prefers the stricter query policy",
+			&Store{entries: map[key]entry{
+				{http.MethodGet, "/search"}:     {body: []byte("any"), query: QueryMatching{mode: queryIgnore}},
+				{http.MethodGet, "/search?q=a"}: {body: []byte("q"), query: QueryMatching{mode: queryParams, params: []string{"q"}}},
+			}},
+			http.MethodGet,
+			"/search?page=2&q=a",
+			check(
+				hasBody("q"),
+			),
+		},
+		{
+			"templates require the same query string",
+			storeWith("/users/{id}?full=true", "template"),
+			http.MethodGet,
+			"/users/42",
+			check(
+				hasOk(false),
+			),
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			h, ok := tc.store.Get(tc.path)
+			h, ok := tc.store.Get(tc.method, tc.path)
+			for _, check := range tc.checks {
+				if err := check(h, ok); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreMatch(t *testing.T) {
+	s := New()
+	s.entries[key{http.MethodGet, "/items/%7Bid%7D"}] = entry{}
+	s.entries[key{http.MethodGet, "/items/1"}] = entry{}
+
+	testCases := [...]struct {
+		method string
+		path   string
+		want   string
+		ok     bool
+	}{
+		{"GET", "/items/1", "/items/1", true},
+		{"GET", "/items/2", "/items/%7Bid%7D", true},
+		{"POST", "/items/2", "", false},
+		{"GET", "/other", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			have, ok := s.Match(tc.method, tc.path)
+			if ok != tc.ok || have != tc.want {
+				t.Errorf("expected %q %v, found %q %v", tc.want, tc.ok, have, ok)
+			}
+		})
+	}
+}
+
+func TestStoreEcho(t *testing.T) {
+	s := New(WithLatency(Latency{kind: latencyFixed, a: time.Hour}))
+	s.entries[key{http.MethodGet, "/a"}] = entry{
+		body:      []byte("0123456789"),
+		latency:   Latency{kind: latencyFixed, a: time.Hour},
+		bandwidth: Bandwidth{set: true, rate: 1},
+	}
+
+	h, ok := s.Echo(http.MethodGet, "/a")
+	if !ok {
+		t.Fatal("expected an entry, found none")
+	}
+
+	done := make(chan string)
+	go func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/a", nil))
+		done <- rec.Body.String()
+	}()
+
+	select {
+	case have := <-done:
+		if want := "0123456789"; want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected neither latency nor throttling")
+	}
+}
+
+func TestStoreList(t *testing.T) {
+	type checkFunc func(http.Handler, bool) error
+	check := func(fns ...checkFunc) []checkFunc { return fns }
+
+	hasBodies := func(want ...string) checkFunc {
+		return func(handler http.Handler, _ bool) error {
+			c := handler.(collection)
+			if len(c) != len(want) {
+				return fmt.Errorf("expected %d entries, found %d", len(want), len(c))
+			}
+			for i := range want {
+				if have := string(c[i].body); have != want[i] {
+					return fmt.Errorf("expected body %q at position %d, found %q", want[i], i, have)
+				}
+			}
+			return nil
+		}
+	}
+	hasOk := func(want bool) checkFunc {
+		return func(_ http.Handler, have bool) error {
+			if have != want {
+				return fmt.Errorf("expected ok %v, found %v", want, have)
+			}
+			return nil
+		}
+	}
+
+	store := &Store{entries: map[key]entry{
+		{http.MethodGet, "/items/10"}:     {body: []byte("ten")},
+		{http.MethodGet, "/items/2"}:      {body: []byte("two")},
+		{http.MethodGet, "/items/1"}:      {body: []byte("one")},
+		{http.MethodPost, "/items/4"}:     {body: []byte("post")},
+		{http.MethodGet, "/items/1/tags"}: {body: []byte("tags")},
+		{http.MethodGet, "/items/3?x=y"}:  {body: []byte("query")},
+		{http.MethodGet, "/other/1"}:      {body: []byte("other")},
+	}}
+
+	testCases := [...]struct {
+		name   string
+		path   string
+		checks []checkFunc
+	}{
+		{
+			"lists the children in order",
+			"/items",
+			check(
+				hasBodies("one", "two", "ten"),
+				hasOk(true),
+			),
+		},
+		{
+			"ignores the trailing slash",
+			"/items/",
+			check(
+				hasBodies("one", "two", "ten"),
+			),
+		},
+		{
+			"ok is false without children",
+			"/items/2",
+			check(
+				hasOk(false),
+			),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, ok := store.List(tc.path)
 			for _, check := range tc.checks {
 				if err := check(h, ok); err != nil {
 					t.Error(err)
@@ -75,7 +305,7 @@ func TestStoreSet(t *testing.T) {
 
 	hasEntry := func(path, want string) checkFunc {
 		return func(s *Store, _ error) error {
-			e, ok := s.entries[path]
+			e, ok := s.entries[key{http.MethodGet, path}]
 			if !ok {
 				return fmt.Errorf("expected entry with path %q", path)
 			}
@@ -95,7 +325,7 @@ func TestStoreSet(t *testing.T) {
 	}
 
 	storeWith := func(path, body string) *Store {
-		return &Store{entries: map[string]entry{path: {body: []byte(body)}}}
+		return &Store{entries: map[key]entry{{http.MethodGet, path}: {body: []byte(body)}}}
 	}
 
 	testCases := [...]struct {
@@ -134,7 +364,7 @@ func TestStoreSet(t *testing.T) {
 				t.Fatalf("creating the request: %v", err)
 			}
 
-			e := tc.store.Set(tc.newPath, req)
+			e := tc.store.Set(http.MethodGet, tc.newPath, req)
 			for _, check := range tc.checks {
 				if err := check(tc.store, e); err != nil {
 					t.Error(err)
@@ -144,13 +374,163 @@ func TestStoreSet(t *testing.T) {
 	}
 }
 
+func TestStoreAdd(t *testing.T) {
+	type checkFunc func(*Store, string, error) error
+	check := func(fns ...checkFunc) []checkFunc { return fns }
+
+	hasEntry := func(path, want string) checkFunc {
+		return func(s *Store, _ string, _ error) error {
+			e, ok := s.entries[key{http.MethodGet, path}]
+			if !ok {
+				return fmt.Errorf("expected entry with path %q", path)
+			}
+			if have := string(e.body); have != want {
+				return fmt.Errorf("expected body %q, found %q", want, have)
+			}
+			return nil
+		}
+	}
+	hasPath := func(want string) checkFunc {
+		return func(_ *Store, have string, _ error) error {
+			if have != want {
+				return fmt.Errorf("expected path %q, found %q", want, have)
+			}
+			return nil
+		}
+	}
+	hasError := func(want error) checkFunc {
+		return func(_ *Store, _ string, have error) error {
+			if have != want {
+				return fmt.Errorf("expected error %v, found %v", want, have)
+			}
+			return nil
+		}
+	}
+
+	storeWith := func(collection string, lastID int, paths ...string) *Store {
+		s := New()
+		s.sequences[collection] = lastID
+		for _, path := range paths {
+			s.entries[key{http.MethodGet, path}] = entry{body: []byte("existing")}
+		}
+		return s
+	}
+
+	testCases := [...]struct {
+		name       string
+		store      *Store
+		collection string
+		newBody    string
+		checks     []checkFunc
+	}{
+		{
+			"starts a new collection at 1",
+			storeWith("/other", 3),
+			"/items",
+			"new entry",
+			check(
+				hasPath("/items/1"),
+				hasEntry("/items/1", "new entry"),
+				hasError(nil),
+			),
+		},
+		{
+			"increments the collection ID",
+			storeWith("/items", 3),
+			"/items",
+			"new entry",
+			check(
+				hasPath("/items/4"),
+				hasEntry("/items/4", "new entry"),
+			),
+		},
+		{
+			"ignores the trailing slash",
+			storeWith("/items", 3),
+			"/items/",
+			"new entry",
+			check(
+				hasPath("/items/4"),
+			),
+		},
+		{
+			"skips the existing entries",
+			storeWith("/items", 0, "/items/1", "/items/2"),
+			"/items",
+			"new entry",
+			check(
+				hasPath("/items/3"),
+				hasEntry("/items/1", "existing"),
+				hasEntry("/items/3", "new entry"),
+			),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", tc.collection, strings.NewReader(tc.newBody))
+			if err != nil {
+				t.Fatalf("creating the request: %v", err)
+			}
+
+			path, e := tc.store.Add(tc.collection, req)
+			for _, check := range tc.checks {
+				if err := check(tc.store, path, e); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func TestStorePatch(t *testing.T) {
+	newStore := func() *Store {
+		return &Store{entries: map[key]entry{
+			{http.MethodGet, "/json"}: {contentType: "application/json", body: []byte(`{"a":1}`)},
+			{http.MethodGet, "/text"}: {contentType: "text/plain", body: []byte(`{"a":1}`)},
+		}}
+	}
+
+	t.Run("patches the entry", func(t *testing.T) {
+		s := newStore()
+		req, _ := http.NewRequest("PATCH", "/json", strings.NewReader(`{"b":2}`))
+		req.Header.Set("Content-Type", MergePatchType)
+		ok, err := s.Patch("/json", req)
+		if !ok || err != nil {
+			t.Fatalf("expected ok and no error, found %v and %v", ok, err)
+		}
+		if want, have := `{"a":1,"b":2}`, string(s.entries[key{http.MethodGet, "/json"}].body); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+	})
+
+	t.Run("ok is false if not found", func(t *testing.T) {
+		req, _ := http.NewRequest("PATCH", "/missing", strings.NewReader(`{}`))
+		if ok, _ := newStore().Patch("/missing", req); ok {
+			t.Error("expected ok to be false")
+		}
+	})
+
+	t.Run("only patches JSON entries", func(t *testing.T) {
+		s := newStore()
+		req, _ := http.NewRequest("PATCH", "/text", strings.NewReader(`{"b":2}`))
+		req.Header.Set("Content-Type", MergePatchType)
+		if _, err := s.Patch("/text", req); !errors.Is(err, ErrPatchConflict) {
+			t.Errorf("expected error %v, found %v", ErrPatchConflict, err)
+		}
+		if want, have := `{"a":1}`, string(s.entries[key{http.MethodGet, "/text"}].body); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+	})
+}
+
 func TestStoreDel(t *testing.T) {
 	type checkFunc func(*Store, bool) error
 	check := func(fns ...checkFunc) []checkFunc { return fns }
 
 	hasNoEntry := func(path string) checkFunc {
 		return func(s *Store, _ bool) error {
-			_, ok := s.entries[path]
+			_, ok := s.entries[key{http.MethodGet, path}]
 			if ok {
 				return fmt.Errorf("unexpected entry with path %q", path)
 			}
@@ -167,7 +547,7 @@ func TestStoreDel(t *testing.T) {
 	}
 
 	storeWith := func(path, body string) *Store {
-		return &Store{entries: map[string]entry{path: {body: []byte(body)}}}
+		return &Store{entries: map[key]entry{{http.MethodGet, path}: {body: []byte(body)}}}
 	}
 
 	testCases := [...]struct {
@@ -198,7 +578,10 @@ func TestStoreDel(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 
-			ok := tc.store.Del(tc.path)
+			ok, err := tc.store.Del(http.MethodGet, tc.path, nil)
+			if err != nil {
+				t.Fatalf("deleting: %v", err)
+			}
 			for _, check := range tc.checks {
 				if err := check(tc.store, ok); err != nil {
 					t.Error(err)
@@ -208,6 +591,122 @@ func TestStoreDel(t *testing.T) {
 	}
 }
 
+func TestStoreRecords(t *testing.T) {
+	s := New()
+	for _, k := range [...]key{{"GET", "/items/10"}, {"GET", "/orders/2"}, {"POST", "/items/2"}, {"GET", "/items/2"}, {"GET", "/carts/2"}} {
+		req, _ := http.NewRequest("PUT", k.path, strings.NewReader("body"))
+		if err := s.Set(k.method, k.path, req); err != nil {
+			t.Fatalf("setting: %v", err)
+		}
+	}
+
+	t.Run("lists the records sorted by key and method", func(t *testing.T) {
+		var have []string
+		for _, r := range s.Records() {
+			have = append(have, r.Method+" "+r.Path)
+		}
+		if want := "GET /carts/2,GET /items/2,POST /items/2,GET /orders/2,GET /items/10"; strings.Join(have, ",") != want {
+			t.Errorf("expected %q, found %q", want, strings.Join(have, ","))
+		}
+	})
+
+	t.Run("returns one record", func(t *testing.T) {
+		r, ok := s.Record("POST", "/items/2")
+		if !ok {
+			t.Fatal("expected a record")
+		}
+		if want, have := "body", string(r.Body); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+		if _, ok := s.Record("DELETE", "/items/2"); ok {
+			t.Error("unexpected record")
+		}
+	})
+}
+
+func TestStoreLoad(t *testing.T) {
+	t.Run("saves the records", func(t *testing.T) {
+		s := New()
+		err := s.Load([]Record{
+			{Method: "GET", Path: "/a", Body: []byte("a"), Latency: "1s"},
+			{Method: "GET", Path: "/b", Body: []byte("b"), Steps: []Record{{Body: []byte("c")}}},
+		})
+		if err != nil {
+			t.Fatalf("loading: %v", err)
+		}
+		if want, have := 2, len(s.entries); want != have {
+			t.Errorf("expected %d entries, found %d", want, have)
+		}
+		if e := s.entries[key{"GET", "/a"}]; e.latency.String() != "1s" || e.modTime.IsZero() {
+			t.Errorf("unexpected entry %+v", e)
+		}
+		if want, have := 1, len(s.entries[key{"GET", "/b"}].next); want != have {
+			t.Errorf("expected %d steps, found %d", want, have)
+		}
+	})
+
+	for _, r := range [...]Record{
+		{Path: "/a"},
+		{Method: "GET", Path: "a"},
+		{Method: "GET", Path: "/a", Status: 42},
+		{Method: "GET", Path: "/a", Query: "fuzzy"},
+		{Method: "GET", Path: "/a", Latency: "slow"},
+		{Method: "GET", Path: "/a", Bandwidth: "fast"},
+		{Method: "GET", Path: "/a", Steps: []Record{{Status: 42}}},
+	} {
+		t.Run(fmt.Sprintf("rejects %+v", r), func(t *testing.T) {
+			s := New()
+			if err := s.Load([]Record{{Method: "GET", Path: "/ok"}, r}); !errors.Is(err, ErrInvalidRecord) {
+				t.Errorf("expected ErrInvalidRecord, found %v", err)
+			}
+			if have := len(s.entries); have != 0 {
+				t.Errorf("expected no entries, found %d", have)
+			}
+		})
+	}
+}
+
+func TestStoreClear(t *testing.T) {
+	s := New()
+	req, _ := http.NewRequest("POST", "/items", strings.NewReader("item"))
+	if _, err := s.Add("/items", req); err != nil {
+		t.Fatalf("adding: %v", err)
+	}
+
+	if err := s.Clear(); err != nil {
+		t.Fatalf("clearing: %v", err)
+	}
+
+	if have := len(s.entries); have != 0 {
+		t.Errorf("expected no entries, found %d", have)
+	}
+	if have := len(s.sequences); have != 0 {
+		t.Errorf("expected no collection IDs, found %d", have)
+	}
+}
+
+func TestStoreReset(t *testing.T) {
+	s := New()
+	setSequence(t, s, "/job", "last", "pending", "done")
+	bodies(s, "/job", 2)
+
+	req, _ := http.NewRequest("POST", "/items", strings.NewReader("item"))
+	if _, err := s.Add("/items", req); err != nil {
+		t.Fatalf("adding: %v", err)
+	}
+
+	if err := s.Reset(); err != nil {
+		t.Fatalf("resetting: %v", err)
+	}
+
+	if want, have := "pending", bodies(s, "/job", 1); want != have {
+		t.Errorf("expected %q, found %q", want, have)
+	}
+	if have := s.sequences["/items"]; have != 0 {
+		t.Errorf("expected the collection IDs to start over, found %d", have)
+	}
+}
+
 func TestWithDefaultContentType(t *testing.T) {
 	t.Run("adds the option", func(t *testing.T) {
 		var s Store
@@ -228,6 +727,80 @@ func TestWithContentTypeOverride(t *testing.T) {
 	})
 }
 
+func TestWithQueryMatching(t *testing.T) {
+	t.Run("adds the option", func(t *testing.T) {
+		var s Store
+		WithQueryMatching(QueryMatching{mode: queryIgnore})(&s)
+		if want, have := "ignore", s.queryMatching.String(); want != have {
+			t.Errorf("expected queryMatching %q, found %q", want, have)
+		}
+	})
+
+	t.Run("defaults to exact", func(t *testing.T) {
+		var s Store
+		WithQueryMatching(QueryMatching{})(&s)
+		if want, have := "exact", s.queryMatching.String(); want != have {
+			t.Errorf("expected queryMatching %q, found %q", want, have)
+		}
+	})
+}
+
+func TestWithLatency(t *testing.T) {
+	t.Run("adds the option", func(t *testing.T) {
+		var s Store
+		WithLatency(Latency{kind: latencyFixed, a: time.Second})(&s)
+		if want, have := "1s", s.latency.String(); want != have {
+			t.Errorf("expected latency %q, found %q", want, have)
+		}
+	})
+
+	t.Run("applies to the entries saved without one", func(t *testing.T) {
+		s := New(WithLatency(Latency{kind: latencyFixed, a: time.Second}))
+		s.entries[key{http.MethodGet, "/default"}] = entry{}
+		s.entries[key{http.MethodGet, "/custom"}] = entry{latency: Latency{kind: latencyFixed}}
+
+		for path, want := range map[string]string{"/default": "1s", "/custom": "0s"} {
+			h, _ := s.Get(http.MethodGet, path)
+			if have := h.(entry).latency.String(); have != want {
+				t.Errorf("%s: expected latency %q, found %q", path, want, have)
+			}
+		}
+	})
+}
+
+func TestWithBandwidth(t *testing.T) {
+	t.Run("adds the option", func(t *testing.T) {
+		var s Store
+		WithBandwidth(Bandwidth{set: true, rate: 1024})(&s)
+		if want, have := "1k", s.bandwidth.String(); want != have {
+			t.Errorf("expected bandwidth %q, found %q", want, have)
+		}
+	})
+
+	t.Run("applies to the entries saved without one", func(t *testing.T) {
+		s := New(WithBandwidth(Bandwidth{set: true, rate: 1024}))
+		s.entries[key{http.MethodGet, "/default"}] = entry{}
+		s.entries[key{http.MethodGet, "/custom"}] = entry{bandwidth: Bandwidth{set: true}}
+
+		for path, want := range map[string]string{"/default": "1k", "/custom": "0"} {
+			h, _ := s.Get(http.MethodGet, path)
+			if have := h.(entry).bandwidth.String(); have != want {
+				t.Errorf("%s: expected bandwidth %q, found %q", path, want, have)
+			}
+		}
+	})
+}
+
+func TestWithPersistence(t *testing.T) {
+	t.Run("adds the option", func(t *testing.T) {
+		var s Store
+		WithPersistence("abc")(&s)
+		if want, have := "abc", s.persistencePath; want != have {
+			t.Errorf("expected persistencePath %q, found %q", want, have)
+		}
+	})
+}
+
 func TestNew(t *testing.T) {
 	t.Run("applies the provided options", func(t *testing.T) {
 		opt1 := option(func(s *Store) {
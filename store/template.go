@@ -0,0 +1,92 @@
+package store
+
+import (
+	"net/url"
+	"strings"
+)
+
+// splitKey separates the path of a key from its query string.
+func splitKey(k string) (path, query string) {
+	if i := strings.IndexByte(k, '?'); i >= 0 {
+		return k[:i], k[i+1:]
+	}
+	return k, ""
+}
+
+// parameterName returns the name of a template parameter segment, such as
+// "id" for "{id}".
+// The returned boolean is false if the segment is not a parameter.
+func parameterName(segment string) (string, bool) {
+	if len(segment) < 3 || segment[0] != '{' || segment[len(segment)-1] != '}' {
+		return "", false
+	}
+	return segment[1 : len(segment)-1], true
+}
+
+// isTemplate reports whether any segment of the key path is a parameter.
+func isTemplate(k string) bool {
+	path, _ := splitKey(k)
+	for _, segment := range strings.Split(path, "/") {
+		if segment, err := url.PathUnescape(segment); err == nil {
+			if _, ok := parameterName(segment); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchTemplate matches a path against a template path, in which the
+// parameter segments (e.g. "{id}") match any single non-empty segment.
+// The returned map holds the value of every parameter.
+// The returned boolean is false if the path does not match the template.
+func matchTemplate(template, path string) (map[string]string, bool) {
+	templateSegments := strings.Split(template, "/")
+	pathSegments := strings.Split(path, "/")
+
+	if len(templateSegments) != len(pathSegments) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, segment := range templateSegments {
+		segment, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, false
+		}
+
+		value, err := url.PathUnescape(pathSegments[i])
+		if err != nil {
+			return nil, false
+		}
+
+		if name, ok := parameterName(segment); ok {
+			if value == "" {
+				return nil, false
+			}
+			params[name] = value
+			continue
+		}
+
+		if segment != value {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// expand substitutes every "{{name}}" placeholder in the body with the value
+// of the corresponding parameter.
+func expand(body []byte, params map[string]string) []byte {
+	if len(params) == 0 {
+		return body
+	}
+
+	pairs := make([]string, 0, 2*len(params))
+	for name, value := range params {
+		pairs = append(pairs, "{{"+name+"}}", value)
+	}
+
+	return []byte(strings.NewReplacer(pairs...).Replace(string(body)))
+}
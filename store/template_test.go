@@ -0,0 +1,71 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIsTemplate(t *testing.T) {
+	testCases := [...]struct {
+		key  string
+		want bool
+	}{
+		{"/users/{id}", true},
+		{"/users/%7Bid%7D", true},
+		{"/users/%7Bid%7D/posts?page=1", true},
+		{"/users/42", false},
+		{"/users/{}", false},
+		{"/search?q={id}", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			if have := isTemplate(tc.key); have != tc.want {
+				t.Errorf("expected %v, found %v", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestMatchTemplate(t *testing.T) {
+	testCases := [...]struct {
+		template string
+		path     string
+		want     map[string]string
+		ok       bool
+	}{
+		{"/users/%7Bid%7D", "/users/1", map[string]string{"id": "1"}, true},
+		{"/users/{id}", "/users/abc", map[string]string{"id": "abc"}, true},
+		{"/users/{id}", "/users/a%20b", map[string]string{"id": "a b"}, true},
+		{"/users/{id}/posts/{post}", "/users/1/posts/2", map[string]string{"id": "1", "post": "2"}, true},
+		{"/users/{id}", "/users/", nil, false},
+		{"/users/{id}", "/users/1/posts", nil, false},
+		{"/users/{id}/posts", "/users/1/comments", nil, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s on %s", tc.path, tc.template), func(t *testing.T) {
+			have, ok := matchTemplate(tc.template, tc.path)
+			if ok != tc.ok {
+				t.Fatalf("expected ok %v, found %v", tc.ok, ok)
+			}
+			if len(have) != len(tc.want) {
+				t.Fatalf("expected params %v, found %v", tc.want, have)
+			}
+			for name, value := range tc.want {
+				if have[name] != value {
+					t.Errorf("expected param %q to be %q, found %q", name, value, have[name])
+				}
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	body := []byte(`{"id": "{{id}}", "name": "user {{id}}", "post": "{{post}}"}`)
+	want := `{"id": "42", "name": "user 42", "post": "{{post}}"}`
+
+	if have := string(expand(body, map[string]string{"id": "42"})); have != want {
+		t.Errorf("expected body %q, found %q", want, have)
+	}
+}
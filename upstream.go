@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/pierreprinetti/apimock/store"
+)
+
+// parseUpstream parses the absolute URL of an upstream server.
+func parseUpstream(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid upstream URL %q", s)
+	}
+	return u, nil
+}
+
+// newReverseProxy returns a reverse proxy to the upstream server. The
+// requests present the upstream host and origin, as if they came from the
+// upstream's own front-end. The CORS headers of the upstream responses are
+// dropped, in favour of the ones set by apimock.
+func newReverseProxy(upstream *url.URL) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(upstream)
+
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = upstream.Host
+		if req.Header.Get("Origin") != "" {
+			req.Header.Set("Origin", upstream.Scheme+"://"+upstream.Host)
+		}
+	}
+
+	proxy.ModifyResponse = func(res *http.Response) error {
+		for name := range res.Header {
+			if strings.HasPrefix(name, "Access-Control-") {
+				res.Header.Del(name)
+			}
+		}
+		return nil
+	}
+
+	return proxy
+}
+
+// passthrough is a middleware handler that serves locally the requests with
+// a saved response, and the requests configuring apimock. It forwards the
+// other requests to an upstream server.
+type passthrough struct {
+	next      http.Handler
+	resources router
+	proxy     *httputil.ReverseProxy
+}
+
+// newPassthrough returns a new passthrough instance
+func newPassthrough(next http.Handler, resources router, upstream *url.URL) passthrough {
+	return passthrough{
+		next:      next,
+		resources: resources,
+		proxy:     newReverseProxy(upstream),
+	}
+}
+
+// local reports whether the request is to be served by apimock.
+func (m passthrough) local(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodOptions:
+		return true
+	case http.MethodGet, http.MethodHead:
+		if _, ok := m.resources.Peek(http.MethodGet, req.URL.String()); ok {
+			return true
+		}
+		_, ok := m.resources.List(req.URL.EscapedPath())
+		return ok
+	default:
+		if isConfiguration(req) {
+			return true
+		}
+		_, ok := m.resources.Peek(req.Method, req.URL.String())
+		return ok
+	}
+}
+
+func (m passthrough) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if m.local(req) {
+		m.next.ServeHTTP(rw, req)
+		return
+	}
+	m.proxy.ServeHTTP(rw, req)
+}
+
+type loader interface {
+	Load([]store.Record) error
+}
+
+// recorder is a handler that forwards the requests to an upstream server,
+// and saves its responses to the GET requests in the store. Only the
+// successful and redirect responses are saved, so that a transient failure,
+// an expired token or a rate limit is not replayed forever.
+type recorder struct {
+	upstream  *url.URL
+	resources loader
+}
+
+// newRecorder returns a new recorder instance
+func newRecorder(upstream *url.URL, resources loader) recorder {
+	return recorder{
+		upstream:  upstream,
+		resources: resources,
+	}
+}
+
+// unreplayableHeaders are the request headers that would make the upstream
+// send a response that can't be replayed to any request: compressed, empty
+// or partial.
+var unreplayableHeaders = [...]string{
+	"Accept-Encoding",
+	"If-Match",
+	"If-Modified-Since",
+	"If-None-Match",
+	"If-Range",
+	"If-Unmodified-Since",
+	"Range",
+}
+
+func (r recorder) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	path := req.URL.String()
+
+	proxy := newReverseProxy(r.upstream)
+	if req.Method == http.MethodGet {
+		director := proxy.Director
+		proxy.Director = func(req *http.Request) {
+			director(req)
+			for _, name := range unreplayableHeaders {
+				req.Header.Del(name)
+			}
+		}
+		modifyResponse := proxy.ModifyResponse
+		proxy.ModifyResponse = func(res *http.Response) error {
+			if err := modifyResponse(res); err != nil {
+				return err
+			}
+			if res.StatusCode < 200 || res.StatusCode >= 400 {
+				return nil
+			}
+			return r.save(path, res)
+		}
+	}
+
+	proxy.ServeHTTP(rw, req)
+}
+
+// save stores the upstream response as the GET response of path.
+func (r recorder) save(path string, res *http.Response) error {
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	header := make(http.Header)
+	for name, values := range res.Header {
+		if !savedHeader(name) {
+			continue
+		}
+		header[name] = append([]string(nil), values...)
+	}
+
+	return r.resources.Load([]store.Record{{
+		Method:      http.MethodGet,
+		Path:        path,
+		ContentType: res.Header.Get("Content-Type"),
+		Body:        body,
+		Status:      res.StatusCode,
+		Header:      header,
+	}})
+}
+
+// savedHeader reports whether the upstream response header is worth saving.
+// The hop-by-hop headers and the ones describing the body encoding are not,
+// and neither are the CORS headers: apimock sets its own.
+func savedHeader(name string) bool {
+	switch name {
+	case "Connection", "Content-Length", "Content-Type", "Date", "Keep-Alive", "Proxy-Authenticate",
+		"Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade":
+		return false
+	}
+	return !strings.HasPrefix(name, "Access-Control-")
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pierreprinetti/apimock/store"
+)
+
+func TestParseUpstream(t *testing.T) {
+	testCases := [...]struct {
+		value string
+		ok    bool
+	}{
+		{"http://localhost:8080", true},
+		{"https://api.example.com/v1", true},
+		{"localhost:8080", false},
+		{"ftp://example.com", false},
+		{"http://", false},
+		{"%", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			if _, err := parseUpstream(tc.value); (err == nil) != tc.ok {
+				t.Errorf("expected ok %v, found error %v", tc.ok, err)
+			}
+		})
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	var received *http.Request
+	upstream := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		received = req
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Cache-Control", "no-cache")
+		rw.Header().Set("Access-Control-Allow-Origin", "example.com")
+		rw.WriteHeader(http.StatusAccepted)
+		rw.Write([]byte(`{"upstream":true}`))
+	}))
+	defer upstream.Close()
+
+	u, _ := url.Parse(upstream.URL + "/v1")
+
+	t.Run("forwards and saves the GET responses", func(t *testing.T) {
+		resources := store.New()
+		h := newRouter(resources, newRecorder(u, resources))
+
+		req := httptest.NewRequest("GET", "/items?page=1", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		req.Header.Set("Range", "bytes=0-1")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if want, have := `{"upstream":true}`, rec.Body.String(); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+		if want, have := "/v1/items", received.URL.Path; want != have {
+			t.Errorf("expected upstream path %q, found %q", want, have)
+		}
+		if want, have := u.Host, received.Host; want != have {
+			t.Errorf("expected upstream host %q, found %q", want, have)
+		}
+		if have := received.Header.Get("Range"); have != "" {
+			t.Errorf("expected no Range header, found %q", have)
+		}
+
+		r, ok := resources.Record("GET", "/items?page=1")
+		if !ok {
+			t.Fatal("expected the response to be saved")
+		}
+		if r.Status != 202 || r.ContentType != "application/json" || string(r.Body) != `{"upstream":true}` {
+			t.Errorf("unexpected record %+v", r)
+		}
+		if want, have := "no-cache", r.Header.Get("Cache-Control"); want != have {
+			t.Errorf("expected saved Cache-Control %q, found %q", want, have)
+		}
+		if have := r.Header.Get("Access-Control-Allow-Origin"); have != "" {
+			t.Errorf("expected no saved CORS header, found %q", have)
+		}
+
+		// The saved response is replayed without calling the upstream
+		received = nil
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/items?page=1", nil))
+		if received != nil {
+			t.Error("expected the upstream not to be called")
+		}
+		if want, have := 202, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+		if want, have := `{"upstream":true}`, rec.Body.String(); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+	})
+
+	t.Run("does not save the HEAD responses", func(t *testing.T) {
+		resources := store.New()
+		h := newRouter(resources, newRecorder(u, resources))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("HEAD", "/items", nil))
+
+		if want, have := 202, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+		if _, ok := resources.Record("GET", "/items"); ok {
+			t.Error("unexpected saved response")
+		}
+	})
+
+	t.Run("does not save the errors", func(t *testing.T) {
+		for _, status := range [...]int{401, 404, 429, 503} {
+			failing := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				http.Error(rw, "try again later", status)
+			}))
+			down, _ := url.Parse(failing.URL)
+
+			resources := store.New()
+			rec := httptest.NewRecorder()
+			newRecorder(down, resources).ServeHTTP(rec, httptest.NewRequest("GET", "/items", nil))
+			failing.Close()
+
+			if want, have := status, rec.Code; want != have {
+				t.Errorf("expected status %d, found %d", want, have)
+			}
+			if want, have := "try again later\n", rec.Body.String(); want != have {
+				t.Errorf("%d: expected body %q, found %q", status, want, have)
+			}
+			if _, ok := resources.Record("GET", "/items"); ok {
+				t.Errorf("%d: unexpected saved response", status)
+			}
+		}
+	})
+
+	t.Run("answers 502 if the upstream is unreachable", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+		down, _ := url.Parse(closed.URL)
+
+		resources := store.New()
+		rec := httptest.NewRecorder()
+		newRecorder(down, resources).ServeHTTP(rec, httptest.NewRequest("GET", "/items", nil))
+
+		if want, have := 502, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+		body, _ := ioutil.ReadAll(rec.Body)
+		if len(body) != 0 {
+			t.Errorf("expected no body, found %q", body)
+		}
+	})
+}
+
+func TestPassthrough(t *testing.T) {
+	var received *http.Request
+	upstream := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		received = req
+		rw.Header().Set("Access-Control-Allow-Origin", "https://front.example.com")
+		rw.Write([]byte("upstream"))
+	}))
+	defer upstream.Close()
+
+	u, _ := url.Parse(upstream.URL)
+
+	resources := store.New()
+	for _, k := range [...][2]string{{"GET", "/mocked"}, {"POST", "/login"}} {
+		req := httptest.NewRequest("PUT", k[1], strings.NewReader("local"))
+		if err := resources.Set(k[0], k[1], req); err != nil {
+			t.Fatalf("setting: %v", err)
+		}
+	}
+
+	h := newCors(newPassthrough(newRouter(resources, nil), resources, u))
+
+	testCases := [...]struct {
+		name   string
+		method string
+		target string
+		header string
+		want   string
+	}{
+		{"serves the saved GET responses", "GET", "/mocked", "", "local"},
+		{"serves the saved responses of other methods", "POST", "/login", "", "local"},
+		{"serves the configuration requests", "PUT", "/new", store.MethodHeader, "local"},
+		{"proxies the GET misses", "GET", "/other", "", "upstream"},
+		{"proxies the other methods", "POST", "/mocked", "", "upstream"},
+		{"proxies the plain PUT requests", "PUT", "/new", "", "upstream"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader("local"))
+			if tc.header != "" {
+				req.Header.Set(tc.header, "GET")
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if want, have := tc.want, rec.Body.String(); want != have {
+				t.Errorf("expected body %q, found %q", want, have)
+			}
+		})
+	}
+
+	t.Run("rewrites the request headers", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/other", nil)
+		req.Header.Set("Origin", "http://localhost:3000")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		if want, have := u.Host, received.Host; want != have {
+			t.Errorf("expected Host %q, found %q", want, have)
+		}
+		if want, have := upstream.URL, received.Header.Get("Origin"); want != have {
+			t.Errorf("expected Origin %q, found %q", want, have)
+		}
+	})
+
+	t.Run("keeps the apimock CORS headers", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/other", nil))
+
+		if want, have := []string{"*"}, rec.Header()["Access-Control-Allow-Origin"]; !reflect.DeepEqual(want, have) {
+			t.Errorf("expected Access-Control-Allow-Origin %q, found %q", want, have)
+		}
+	})
+}
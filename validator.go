@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pierreprinetti/apimock/openapi"
+)
+
+// Validator is a middleware handler that rejects the requests that do not
+// conform to the operations declared in an OpenAPI document. The requests
+// configuring apimock and the pre-flight requests are not validated.
+type Validator struct {
+	next http.Handler
+	doc  *openapi.Document
+}
+
+// newValidator returns a new Validator instance
+func newValidator(next http.Handler, doc *openapi.Document) Validator {
+	return Validator{
+		next: next,
+		doc:  doc,
+	}
+}
+
+func (m Validator) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodOptions || isConfiguration(req) {
+		m.next.ServeHTTP(rw, req)
+		return
+	}
+
+	if err := m.doc.Validate(req); err != nil {
+		var invalid *openapi.ValidationError
+		if !errors.As(err, &invalid) {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m.next.ServeHTTP(rw, req)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pierreprinetti/apimock/openapi"
+	"github.com/pierreprinetti/apimock/store"
+)
+
+// errReader is a Reader that fails with err.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func TestValidator(t *testing.T) {
+	doc, err := openapi.Parse([]byte(`{
+		"openapi": "3.0.3",
+		"paths": {"/users/{id}": {"put": {
+			"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}],
+			"requestBody": {"required": true, "content": {"application/json": {"schema": {
+				"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}
+			}}}}
+		}}}
+	}`))
+	if err != nil {
+		t.Fatalf("parsing the document: %v", err)
+	}
+
+	next := http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.Write([]byte("next"))
+	})
+
+	testCases := [...]struct {
+		name       string
+		method     string
+		target     string
+		header     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"passes valid requests on", "PUT", "/users/1", "", `{"name":"a"}`, 200, "next"},
+		{"rejects invalid bodies", "PUT", "/users/1", "", `{}`, 400, "request body: missing required property \"name\"\n"},
+		{"rejects invalid parameters", "PUT", "/users/a", "", `{"name":"a"}`, 400, "path parameter \"id\": \"a\" is not a number\n"},
+		{"spares configuration requests", "PUT", "/users/a", store.MethodHeader, `{}`, 200, "next"},
+		{"spares pre-flight requests", "OPTIONS", "/users/a", "", ``, 200, "next"},
+		{"spares undeclared operations", "POST", "/users/a", "", `{}`, 200, "next"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.header != "" {
+				req.Header.Set(tc.header, "GET")
+			}
+
+			rec := httptest.NewRecorder()
+			newValidator(next, doc).ServeHTTP(rec, req)
+
+			if want, have := tc.wantStatus, rec.Code; want != have {
+				t.Errorf("expected status %d, found %d", want, have)
+			}
+			if want, have := tc.wantBody, rec.Body.String(); want != have {
+				t.Errorf("expected body %q, found %q", want, have)
+			}
+		})
+	}
+
+	t.Run("answers 500 on internal errors", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/users/1", nil)
+		req.Body = ioutil.NopCloser(errReader{errors.New("connection reset")})
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		newValidator(next, doc).ServeHTTP(rec, req)
+
+		if want, have := 500, rec.Code; want != have {
+			t.Errorf("expected status %d, found %d", want, have)
+		}
+	})
+}
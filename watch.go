@@ -0,0 +1,118 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// fixture identifies a version of a file in the fixtures directory.
+type fixture struct {
+	file    string
+	modTime time.Time
+	size    int64
+}
+
+// scanFixtures returns the current version of every fixture, by key.
+func scanFixtures(dir string) (map[string]fixture, error) {
+	fixtures := make(map[string]fixture)
+	err := walkFixtures(dir, func(key, file string, info os.FileInfo) error {
+		fixtures[key] = fixture{file: file, modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return fixtures, err
+}
+
+// fixturesWatcher polls the fixtures directory and applies the changes to
+// the store.
+//
+// A change is only applied once the file has been left untouched for a whole
+// polling interval, so that a file being written is not loaded half-way.
+type fixturesWatcher struct {
+	resources router
+	dir       string
+	logger    *log.Logger
+
+	// loaded holds the fixtures as they are in the store.
+	loaded map[string]fixture
+
+	// scanned holds the fixtures as they were found by the previous poll.
+	scanned map[string]fixture
+}
+
+// newFixturesWatcher returns a watcher that considers the current content of
+// the fixtures directory as already loaded.
+func newFixturesWatcher(resources router, dir string, logger *log.Logger) (*fixturesWatcher, error) {
+	fixtures, err := scanFixtures(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fixturesWatcher{
+		resources: resources,
+		dir:       dir,
+		logger:    logger,
+		loaded:    fixtures,
+		scanned:   fixtures,
+	}, nil
+}
+
+// poll scans the fixtures directory and applies to the store the changes
+// that were already found by the previous poll.
+func (w *fixturesWatcher) poll() {
+	fixtures, err := scanFixtures(w.dir)
+	if err != nil {
+		w.logger.Printf("scanning the fixtures: %v", err)
+		return
+	}
+
+	for key, f := range fixtures {
+		if f == w.loaded[key] || f != w.scanned[key] {
+			continue
+		}
+
+		if err := loadFixture(w.resources, key, f.file); err != nil {
+			w.logger.Printf("reloading %s: %v", key, err)
+			continue
+		}
+
+		w.loaded[key] = f
+		w.logger.Printf("reloaded %s from %s", key, f.file)
+	}
+
+	for key := range w.loaded {
+		if _, ok := fixtures[key]; ok {
+			continue
+		}
+		if _, ok := w.scanned[key]; ok {
+			continue
+		}
+
+		if _, err := w.resources.Del(http.MethodGet, key, nil); err != nil {
+			w.logger.Printf("removing %s: %v", key, err)
+			continue
+		}
+
+		delete(w.loaded, key)
+		w.logger.Printf("removed %s", key)
+	}
+
+	w.scanned = fixtures
+}
+
+// watch polls the fixtures directory at every interval, until done is
+// closed.
+func (w *fixturesWatcher) watch(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.poll()
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pierreprinetti/apimock/store"
+)
+
+func TestFixturesWatcherPoll(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"users/1.json": `{"id": 1}`,
+		"users/2.json": `{"id": 2}`,
+	})
+	defer os.RemoveAll(dir)
+
+	var logs bytes.Buffer
+	resources := store.New()
+	w, err := newFixturesWatcher(resources, dir, log.New(&logs, "", 0))
+	if err != nil {
+		t.Fatalf("creating the watcher: %v", err)
+	}
+	if err := loadFixtures(resources, dir); err != nil {
+		t.Fatalf("loading the fixtures: %v", err)
+	}
+
+	bodyOf := func(key string) string {
+		h, ok := resources.Get(http.MethodGet, key)
+		if !ok {
+			return ""
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", key, nil))
+		return rec.Body.String()
+	}
+
+	// Change a file, add one and remove one
+	changed := filepath.Join(dir, "users", "1.json")
+	if err := ioutil.WriteFile(changed, []byte(`{"id": "one"}`), 0644); err != nil {
+		t.Fatalf("writing the fixture: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(changed, later, later); err != nil {
+		t.Fatalf("changing the fixture time: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "users", "3.json"), []byte(`{"id": 3}`), 0644); err != nil {
+		t.Fatalf("writing the fixture: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "users", "2.json")); err != nil {
+		t.Fatalf("removing the fixture: %v", err)
+	}
+
+	t.Run("waits for the changes to settle", func(t *testing.T) {
+		w.poll()
+
+		if want, have := `{"id": 1}`, bodyOf("/users/1"); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+		if want, have := "", bodyOf("/users/3"); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+		if want, have := `{"id": 2}`, bodyOf("/users/2"); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+	})
+
+	t.Run("applies the settled changes", func(t *testing.T) {
+		w.poll()
+
+		if want, have := `{"id": "one"}`, bodyOf("/users/1"); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+		if want, have := `{"id": 3}`, bodyOf("/users/3"); want != have {
+			t.Errorf("expected body %q, found %q", want, have)
+		}
+		if _, ok := resources.Get(http.MethodGet, "/users/2"); ok {
+			t.Errorf("unexpected entry %q", "/users/2")
+		}
+	})
+
+	t.Run("logs every reload", func(t *testing.T) {
+		for _, want := range []string{"reloaded /users/1", "reloaded /users/3", "removed /users/2"} {
+			if !strings.Contains(logs.String(), want) {
+				t.Errorf("expected log line %q in %q", want, logs.String())
+			}
+		}
+	})
+
+	t.Run("does not reload unchanged files", func(t *testing.T) {
+		logs.Reset()
+		w.poll()
+
+		if have := logs.String(); have != "" {
+			t.Errorf("expected no log, found %q", have)
+		}
+	})
+}